@@ -0,0 +1,522 @@
+package gowl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error codes returned by failures to parse an address.
+var (
+	ErrInvalidAddress = errors.New("the address is not a valid RFC 5322 address")
+	ErrNoField        = errors.New("the Header has no such field")
+)
+
+// Address represents an RFC 5322 address. It is either a mailbox with an optional
+// display name and an addr-spec, or a named group of mailboxes.
+type Address struct {
+	name    string
+	address string
+	members []*Address
+	group   bool
+}
+
+// NewAddress is a constructor of a mailbox Address, e.g.
+// NewAddress("John Doe", "john.doe@example.com").
+func NewAddress(name, address string) *Address {
+	return &Address{
+		name:    name,
+		address: address,
+	}
+}
+
+// NewGroup is a constructor of a group Address with the given members.
+func NewGroup(name string, members []*Address) *Address {
+	return &Address{
+		name:    name,
+		members: members,
+		group:   true,
+	}
+}
+
+// Reset resets the value of the Address but it keeps its instance (pointer).
+func (a *Address) Reset() {
+	*a = Address{}
+}
+
+// Name returns the decoded display name of the Address.
+func (a *Address) Name() string {
+	return a.name
+}
+
+// Address returns the addr-spec of the mailbox, it is empty for a group.
+func (a *Address) Address() string {
+	return a.address
+}
+
+// Members returns the mailboxes of the group.
+func (a *Address) Members() []*Address {
+	return a.members
+}
+
+// IsGroup reports whether the Address is a group.
+func (a *Address) IsGroup() bool {
+	return a.group
+}
+
+// SetName replaces the display name of the Address.
+func (a *Address) SetName(name string) {
+	a.name = name
+}
+
+// SetAddress replaces the addr-spec of the mailbox.
+func (a *Address) SetAddress(address string) {
+	a.address = address
+}
+
+// SetMembers replaces the mailboxes of the group.
+func (a *Address) SetMembers(members []*Address) {
+	a.members = members
+}
+
+// String formats the Address as in a header field, the display name is quoted
+// if necessary but it is not encoded, e.g. `"Doe, John" <john.doe@example.com>`.
+func (a *Address) String() string {
+	return a.format(false)
+}
+
+// format formats the Address, non-ASCII display names are encoded into RFC 2047
+// encoded words if encode is set.
+func (a *Address) format(encode bool) string {
+	name := formatPhrase(a.name, encode)
+
+	if a.group && len(a.members) == 0 {
+		return name + ":;"
+	}
+
+	if a.group {
+		members := make([]string, len(a.members))
+		for i, m := range a.members {
+			members[i] = m.format(encode)
+		}
+
+		return name + ": " + strings.Join(members, ", ") + ";"
+	}
+
+	if name == "" {
+		return "<" + a.address + ">"
+	}
+
+	return name + " <" + a.address + ">"
+}
+
+// formatPhrase returns the display name as a phrase, it is quoted if it contains
+// specials. Non-ASCII names are encoded into RFC 2047 encoded words if encode is set.
+func formatPhrase(name string, encode bool) string {
+	if name == "" {
+		return ""
+	}
+
+	if encode {
+		if encoded := encodeWord(name, true); encoded != name {
+			return encoded
+		}
+	}
+
+	words := strings.Split(name, " ")
+	for _, w := range words {
+		if w == "" || !isAtom(w) {
+			return quote(name)
+		}
+	}
+
+	return name
+}
+
+// isAtext reports whether c is an atom character, non-ASCII bytes are accepted (RFC 6532).
+func isAtext(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c >= 0x80 || strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+func isAtom(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isAtext(s[i]) {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+func isDotAtom(s string) bool {
+	for _, a := range strings.Split(s, ".") {
+		if !isAtom(a) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// formatAddressList formats the addresses as a comma separated list.
+func formatAddressList(addresses []*Address, encode bool) string {
+	list := make([]string, len(addresses))
+	for i, a := range addresses {
+		list[i] = a.format(encode)
+	}
+
+	return strings.Join(list, ", ")
+}
+
+// ParseAddress parses a single RFC 5322 address, a mailbox or a group. Quoted local
+// parts, comments and RFC 2047 encoded display names are supported. If a mailbox has
+// no display name, the comment following it is used, e.g. "john@example.com (John)".
+func ParseAddress(address string) (*Address, error) {
+	p := &addressParser{s: address}
+
+	a, err := p.parseAddress(true)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.skipCFWS(); p.pos < len(p.s) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidAddress, p.s[p.pos:])
+	}
+
+	return a, nil
+}
+
+// ParseAddressList parses a comma separated list of RFC 5322 addresses.
+func ParseAddressList(list string) ([]*Address, error) {
+	p := &addressParser{s: list}
+
+	var addresses []*Address
+
+	for {
+		p.skipCFWS()
+
+		if p.pos == len(p.s) {
+			break
+		}
+
+		// Empty elements of the list are allowed by the obsolete syntax.
+		if p.s[p.pos] == ',' {
+			p.pos++
+
+			continue
+		}
+
+		a, err := p.parseAddress(true)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, a)
+
+		if p.skipCFWS(); p.pos < len(p.s) {
+			if p.s[p.pos] != ',' {
+				return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidAddress, p.s[p.pos:])
+			}
+
+			p.pos++
+		}
+	}
+
+	if addresses == nil {
+		return nil, fmt.Errorf("%w: empty address list", ErrInvalidAddress)
+	}
+
+	return addresses, nil
+}
+
+// SetAddressList replaces all fields with the given name by a field with the
+// list of the addresses. Non-ASCII display names are encoded into RFC 2047
+// encoded words and the list is folded when the Header is rendered.
+func (h *Header) SetAddressList(name string, addresses ...*Address) {
+	h.Set(name, []string{formatAddressList(addresses, true)})
+}
+
+// AddressList parses the addresses of all fields with the given name.
+func (h *Header) AddressList(name string) ([]*Address, error) {
+	fields := h.GetAll(name)
+	if fields == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoField, name)
+	}
+
+	var values []string
+	for _, f := range fields {
+		values = append(values, f.values...)
+	}
+
+	addresses, err := ParseAddressList(strings.Join(values, ", "))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s field: %w", name, err)
+	}
+
+	return addresses, nil
+}
+
+// addressParser is a recursive descent parser of the RFC 5322 address syntax.
+type addressParser struct {
+	s       string
+	pos     int
+	comment string
+}
+
+// phraseWord is a word of a display name.
+type phraseWord struct {
+	text   string
+	quoted bool
+}
+
+// parseAddress parses a mailbox, or a group if group is set.
+func (p *addressParser) parseAddress(group bool) (*Address, error) {
+	start := p.pos
+
+	words, err := p.parsePhrase()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipCFWS()
+
+	switch {
+	case p.consume('<'):
+		address, err := p.parseAddrSpec()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.skipCFWS(); !p.consume('>') {
+			return nil, fmt.Errorf("%w: missing '>' in %q", ErrInvalidAddress, p.s)
+		}
+
+		return NewAddress(decodePhrase(words), address), nil
+	case group && len(words) > 0 && p.consume(':'):
+		return p.parseGroup(decodePhrase(words))
+	}
+
+	// The phrase was the local part of a bare addr-spec.
+	p.pos = start
+
+	address, err := p.parseAddrSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	p.comment = ""
+	p.skipCFWS()
+
+	return NewAddress(decodePhrase([]phraseWord{{text: p.comment}}), address), nil
+}
+
+// parseGroup parses the members of a group up to the terminating semicolon.
+func (p *addressParser) parseGroup(name string) (*Address, error) {
+	members := []*Address{}
+
+	for {
+		p.skipCFWS()
+
+		switch {
+		case p.consume(';'):
+			return NewGroup(name, members), nil
+		case p.consume(','):
+			continue
+		case p.pos == len(p.s):
+			return nil, fmt.Errorf("%w: missing ';' after group %q", ErrInvalidAddress, name)
+		}
+
+		m, err := p.parseAddress(false)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, m)
+	}
+}
+
+// parsePhrase parses the words of a display name. Dots are accepted in the
+// words as in the obsolete phrase syntax, e.g. "John Q. Public".
+func (p *addressParser) parsePhrase() ([]phraseWord, error) {
+	var words []phraseWord
+
+	for {
+		p.skipCFWS()
+
+		if p.pos < len(p.s) && p.s[p.pos] == '"' {
+			text, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+
+			words = append(words, phraseWord{text: text, quoted: true})
+
+			continue
+		}
+
+		word := p.parseAtom(true)
+		if word == "" {
+			return words, nil
+		}
+
+		words = append(words, phraseWord{text: word})
+	}
+}
+
+// parseAddrSpec parses the "local-part@domain" address.
+func (p *addressParser) parseAddrSpec() (string, error) {
+	var (
+		local string
+		err   error
+	)
+
+	if p.skipCFWS(); p.pos < len(p.s) && p.s[p.pos] == '"' {
+		if local, err = p.parseQuoted(); err != nil {
+			return "", err
+		}
+	} else if local = p.parseAtom(true); local == "" {
+		return "", fmt.Errorf("%w: missing local part in %q", ErrInvalidAddress, p.s)
+	}
+
+	if p.skipCFWS(); !p.consume('@') {
+		return "", fmt.Errorf("%w: missing '@' in %q", ErrInvalidAddress, p.s)
+	}
+
+	p.skipCFWS()
+
+	var domain string
+
+	if p.pos < len(p.s) && p.s[p.pos] == '[' {
+		end := strings.IndexByte(p.s[p.pos:], ']')
+		if end < 0 {
+			return "", fmt.Errorf("%w: missing ']' in %q", ErrInvalidAddress, p.s)
+		}
+
+		domain = p.s[p.pos : p.pos+end+1]
+		p.pos += end + 1
+	} else if domain = p.parseAtom(true); !isDotAtom(domain) {
+		return "", fmt.Errorf("%w: invalid domain in %q", ErrInvalidAddress, p.s)
+	}
+
+	if !isDotAtom(local) {
+		local = quote(local)
+	}
+
+	return local + "@" + domain, nil
+}
+
+// parseAtom parses a sequence of atom characters, and dots if dot is set.
+func (p *addressParser) parseAtom(dot bool) string {
+	start := p.pos
+
+	for p.pos < len(p.s) && (isAtext(p.s[p.pos]) || dot && p.s[p.pos] == '.') {
+		p.pos++
+	}
+
+	return p.s[start:p.pos]
+}
+
+// parseQuoted parses a quoted string and returns its unescaped content.
+func (p *addressParser) parseQuoted() (string, error) {
+	var b strings.Builder
+
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		switch c := p.s[p.pos]; c {
+		case '"':
+			p.pos++
+
+			return b.String(), nil
+		case '\\':
+			if p.pos++; p.pos < len(p.s) {
+				b.WriteByte(p.s[p.pos])
+			}
+		case '\r', '\n':
+			// Folding whitespace in the quoted string.
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", fmt.Errorf("%w: unterminated quoted string in %q", ErrInvalidAddress, p.s)
+}
+
+// skipCFWS skips whitespaces and comments, the text of the last comment is kept.
+func (p *addressParser) skipCFWS() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '(':
+			p.comment = p.parseComment()
+		default:
+			return
+		}
+	}
+}
+
+// parseComment parses a possibly nested comment and returns its text.
+func (p *addressParser) parseComment() string {
+	var b strings.Builder
+
+	depth := 0
+
+	for ; p.pos < len(p.s); p.pos++ {
+		c := p.s[p.pos]
+
+		switch {
+		case c == '\\' && p.pos+1 < len(p.s):
+			p.pos++
+			b.WriteByte(p.s[p.pos])
+		case c == '(':
+			if depth > 0 {
+				b.WriteByte(c)
+			}
+
+			depth++
+		case c == ')':
+			if depth--; depth == 0 {
+				p.pos++
+
+				return strings.TrimSpace(b.String())
+			}
+
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// consume skips the character c if it is the next one.
+func (p *addressParser) consume(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+// decodePhrase joins the words of a display name and decodes its RFC 2047
+// encoded words. Undecodable words are kept.
+func decodePhrase(words []phraseWord) string {
+	texts := make([]string, 0, len(words))
+	for _, w := range words {
+		if w.text != "" || w.quoted {
+			texts = append(texts, w.text)
+		}
+	}
+
+	name := strings.Join(texts, " ")
+	if strings.Contains(name, "=?") {
+		if decoded, err := DecodeHeader(name); err == nil {
+			return decoded
+		}
+	}
+
+	return name
+}
@@ -0,0 +1,251 @@
+package gowl_test
+
+import (
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		address string
+		want    *gowl.Address
+		wantErr error
+	}{
+		{
+			name:    "addr-spec",
+			address: "john.doe@example.com",
+			want:    gowl.NewAddress("", "john.doe@example.com"),
+		},
+		{
+			name:    "angle-addr",
+			address: "<john.doe@example.com>",
+			want:    gowl.NewAddress("", "john.doe@example.com"),
+		},
+		{
+			name:    "display name",
+			address: "John Q. Public <john.q.public@example.com>",
+			want:    gowl.NewAddress("John Q. Public", "john.q.public@example.com"),
+		},
+		{
+			name:    "quoted display name",
+			address: `"Doe, John \"JD\"" <john.doe@example.com>`,
+			want:    gowl.NewAddress(`Doe, John "JD"`, "john.doe@example.com"),
+		},
+		{
+			name:    "encoded display name",
+			address: "=?UTF-8?b?Wm/DqyBNw7xsbGVy?= <zoe@example.com>",
+			want:    gowl.NewAddress("Zoë Müller", "zoe@example.com"),
+		},
+		{
+			name:    "utf-8 display name",
+			address: "Zoë Müller <zoe@example.com>",
+			want:    gowl.NewAddress("Zoë Müller", "zoe@example.com"),
+		},
+		{
+			name:    "quoted local part",
+			address: `"john doe"@example.com`,
+			want:    gowl.NewAddress("", `"john doe"@example.com`),
+		},
+		{
+			name:    "quoted dot-atom local part",
+			address: `"john.doe"@example.com`,
+			want:    gowl.NewAddress("", "john.doe@example.com"),
+		},
+		{
+			name:    "comments",
+			address: "John (the man) Doe <john.doe (comment) @ example.com> (work)",
+			want:    gowl.NewAddress("John Doe", "john.doe@example.com"),
+		},
+		{
+			name:    "comment as name",
+			address: "john.doe@example.com (John (JD) Doe)",
+			want:    gowl.NewAddress("John (JD) Doe", "john.doe@example.com"),
+		},
+		{
+			name:    "domain literal",
+			address: "<postmaster@[192.0.2.1]>",
+			want:    gowl.NewAddress("", "postmaster@[192.0.2.1]"),
+		},
+		{
+			name:    "group",
+			address: "Friends: John <john@example.com>, jane@example.com;",
+			want: gowl.NewGroup("Friends", []*gowl.Address{
+				gowl.NewAddress("John", "john@example.com"),
+				gowl.NewAddress("", "jane@example.com"),
+			}),
+		},
+		{
+			name:    "empty group",
+			address: "undisclosed-recipients:;",
+			want:    gowl.NewGroup("undisclosed-recipients", []*gowl.Address{}),
+		},
+		{
+			name:    "missing at",
+			address: "John <john.doe>",
+			wantErr: gowl.ErrInvalidAddress,
+		},
+		{
+			name:    "missing angle bracket",
+			address: "John <john@example.com",
+			wantErr: gowl.ErrInvalidAddress,
+		},
+		{
+			name:    "unterminated quoted string",
+			address: `"John <john@example.com>`,
+			wantErr: gowl.ErrInvalidAddress,
+		},
+		{
+			name:    "unterminated group",
+			address: "Friends: john@example.com",
+			wantErr: gowl.ErrInvalidAddress,
+		},
+		{
+			name:    "nested group",
+			address: "Friends: Family: john@example.com;;",
+			wantErr: gowl.ErrInvalidAddress,
+		},
+		{
+			name:    "trailing data",
+			address: "john@example.com, jane@example.com",
+			wantErr: gowl.ErrInvalidAddress,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := gowl.ParseAddress(tt.address)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseAddressList(t *testing.T) {
+	t.Parallel()
+
+	got, err := gowl.ParseAddressList(`"Doe, John" <john@example.com>,, jane@example.com (Jane), Team: a@example.com;`)
+	require.NoError(t, err)
+	require.Equal(t, []*gowl.Address{
+		gowl.NewAddress("Doe, John", "john@example.com"),
+		gowl.NewAddress("Jane", "jane@example.com"),
+		gowl.NewGroup("Team", []*gowl.Address{gowl.NewAddress("", "a@example.com")}),
+	}, got)
+
+	_, err = gowl.ParseAddressList(" , ")
+	require.ErrorIs(t, err, gowl.ErrInvalidAddress)
+
+	_, err = gowl.ParseAddressList("john@example.com jane@example.com")
+	require.ErrorIs(t, err, gowl.ErrInvalidAddress)
+}
+
+func TestAddress_String(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		address *gowl.Address
+		want    string
+	}{
+		{
+			name:    "no name",
+			address: gowl.NewAddress("", "john.doe@example.com"),
+			want:    "<john.doe@example.com>",
+		},
+		{
+			name:    "atom name",
+			address: gowl.NewAddress("John Doe", "john.doe@example.com"),
+			want:    "John Doe <john.doe@example.com>",
+		},
+		{
+			name:    "specials",
+			address: gowl.NewAddress(`Doe, John "JD"`, "john.doe@example.com"),
+			want:    `"Doe, John \"JD\"" <john.doe@example.com>`,
+		},
+		{
+			name:    "utf-8 name",
+			address: gowl.NewAddress("Zoë Müller", "zoe@example.com"),
+			want:    "Zoë Müller <zoe@example.com>",
+		},
+		{
+			name: "group",
+			address: gowl.NewGroup("Team A", []*gowl.Address{
+				gowl.NewAddress("John", "john@example.com"),
+				gowl.NewAddress("", "jane@example.com"),
+			}),
+			want: "Team A: John <john@example.com>, <jane@example.com>;",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, tt.address.String())
+
+			parsed, err := gowl.ParseAddress(tt.address.String())
+			require.NoError(t, err)
+			require.Equal(t, tt.address.String(), parsed.String())
+		})
+	}
+}
+
+func TestHeader_SetAddressList(t *testing.T) {
+	t.Parallel()
+
+	recipients := []*gowl.Address{
+		gowl.NewAddress("Zoë Müller", "zoe@example.com"),
+		gowl.NewAddress("Doe, John", "john.doe@example.com"),
+		gowl.NewAddress("Jane Roe", "jane.roe@example.com"),
+		gowl.NewAddress("Max Mustermann", "max@example.com"),
+	}
+
+	h := gowl.NewHeader(nil)
+	h.SetAddressList("To", recipients...)
+	h.SetAddressList("Cc", gowl.NewGroup("undisclosed-recipients", nil))
+
+	got, err := h.Render()
+	require.NoError(t, err)
+	require.Equal(t, string(crlf(`To: =?UTF-8?b?Wm/DqyBNw7xsbGVy?= <zoe@example.com>,
+ "Doe, John" <john.doe@example.com>, Jane Roe <jane.roe@example.com>,
+ Max Mustermann <max@example.com>
+Cc: undisclosed-recipients:;`)), string(got))
+
+	to, err := h.AddressList("to")
+	require.NoError(t, err)
+	require.Equal(t, recipients, to)
+
+	h.AddField(gowl.NewField("To", []string{"extra@example.com"}))
+	to, err = h.AddressList("To")
+	require.NoError(t, err)
+	require.Len(t, to, 5)
+
+	_, err = h.AddressList("Bcc")
+	require.ErrorIs(t, err, gowl.ErrNoField)
+
+	h.Set("Cc", []string{"Team Zoë: Zoë <zoe@example.com>;"})
+	got, err = h.Get("Cc").Render()
+	require.NoError(t, err)
+	require.Equal(t, "Cc: =?UTF-8?b?VGVhbSBab8Or?=: =?UTF-8?q?Zo=C3=AB?= <zoe@example.com>;", string(got))
+
+	h.Set("From", []string{"invalid"})
+	_, err = h.AddressList("From")
+	require.ErrorIs(t, err, gowl.ErrInvalidAddress)
+}
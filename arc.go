@@ -0,0 +1,484 @@
+package gowl
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes returned by failures to seal or validate an ARC chain.
+var (
+	ErrInvalidARCChain = errors.New("the ARC chain is malformed")
+	ErrARCChainFailed  = errors.New("the ARC chain was marked as failed by a sealer")
+	ErrARCSeal         = errors.New("the ARC seal does not match the message")
+	ErrARCLimit        = errors.New("the ARC chain exceeds the limit of 50 sets")
+)
+
+// maxARCInstances is the maximum number of the ARC sets of a message.
+const maxARCInstances = 50
+
+// ARCStatus is a chain validation status of ARC (RFC 8617).
+type ARCStatus int
+
+// Chain validation statuses of ARC.
+const (
+	// ARCNone is the status of a message without an ARC chain.
+	ARCNone ARCStatus = iota
+	// ARCPass is the status of a valid ARC chain.
+	ARCPass
+	// ARCFail is the status of an invalid ARC chain.
+	ARCFail
+)
+
+// String returns the name of the ARCStatus used in the cv= tag and the Authentication-Results field.
+func (s ARCStatus) String() string {
+	switch s {
+	case ARCPass:
+		return "pass"
+	case ARCFail:
+		return "fail"
+	default:
+		return "none"
+	}
+}
+
+// ARCResult is the result of the validation of an ARC chain.
+type ARCResult struct {
+	// Status is the chain validation status.
+	Status ARCStatus
+	// Instance is the instance of the last ARC set, it is 0 if there is none.
+	Instance int
+	// Err is the reason the chain did not pass, it is nil if it passed or there is none.
+	Err error
+}
+
+// ARCSealer adds the ARC sets (RFC 8617) to messages on behalf of a domain,
+// e.g. of a mailing list which modifies the messages it forwards.
+type ARCSealer struct {
+	domain   string
+	selector string
+	key      crypto.Signer
+	headers  []string
+}
+
+// NewARCSealer is a constructor of the ARCSealer. The key is an *rsa.PrivateKey or
+// an ed25519.PrivateKey whose public key is published in DNS at the selector of the
+// domain as for DKIM. By default, the ARC-Message-Signature signs the same fields
+// as the DKIMSigner.
+func NewARCSealer(domain, selector string, key crypto.Signer) *ARCSealer {
+	return &ARCSealer{
+		domain:   domain,
+		selector: selector,
+		key:      key,
+		headers:  dkimSignedHeaders,
+	}
+}
+
+// Reset resets the value of the ARCSealer but it keeps its instance (pointer).
+func (s *ARCSealer) Reset() {
+	*s = ARCSealer{}
+}
+
+// Domain returns the sealing domain (the d= tag).
+func (s *ARCSealer) Domain() string {
+	return s.domain
+}
+
+// Selector returns the selector of the public key (the s= tag).
+func (s *ARCSealer) Selector() string {
+	return s.selector
+}
+
+// Key returns the private key of the ARCSealer.
+func (s *ARCSealer) Key() crypto.Signer {
+	return s.key
+}
+
+// Headers returns the names of the fields signed by the ARC-Message-Signature.
+func (s *ARCSealer) Headers() []string {
+	return s.headers
+}
+
+// SetDomain replaces the sealing domain.
+func (s *ARCSealer) SetDomain(domain string) {
+	s.domain = domain
+}
+
+// SetSelector replaces the selector of the public key.
+func (s *ARCSealer) SetSelector(selector string) {
+	s.selector = selector
+}
+
+// SetKey replaces the private key of the ARCSealer.
+func (s *ARCSealer) SetKey(key crypto.Signer) {
+	s.key = key
+}
+
+// SetHeaders replaces the names of the fields signed by the ARC-Message-Signature,
+// the From field is always signed.
+func (s *ARCSealer) SetHeaders(headers []string) {
+	s.headers = headers
+}
+
+// Seal adds the next ARC set at the top of the Message header: the
+// ARC-Authentication-Results field with the authentication results, e.g.
+// "mx.example.org; dkim=pass header.d=example.com; arc=none", the
+// ARC-Message-Signature field signing the Message as DKIMSigner.Sign does and
+// the ARC-Seal field sealing the chain. The chain is the status of the existing
+// chain as returned by ARCValidator.Validate, it is ignored for the first set.
+func (s *ARCSealer) Seal(m *Message, authResults string, chain ARCStatus) error {
+	algorithm, err := dkimAlgorithm(s.key)
+	if err != nil {
+		return err
+	}
+
+	sets, err := arcSets(m.header.fields)
+	if err != nil {
+		return err
+	}
+
+	instance := len(sets) + 1
+	if instance > maxARCInstances {
+		return ErrARCLimit
+	}
+
+	cv := ARCNone
+	if instance > 1 {
+		if chain == ARCNone {
+			return fmt.Errorf("%w: the chain status of instance %d must be pass or fail", ErrInvalidARCChain, instance)
+		}
+
+		cv = chain
+	}
+
+	aarLine, err := foldField("ARC-Authentication-Results: i=" + strconv.Itoa(instance) + "; " + authResults)
+	if err != nil {
+		return err
+	}
+
+	aar := rawField(aarLine)
+	m.header.prependField(aar)
+
+	signer := &DKIMSigner{
+		domain:   s.domain,
+		selector: s.selector,
+		key:      s.key,
+		headerC:  CanonicalizationRelaxed,
+		bodyC:    CanonicalizationRelaxed,
+		headers:  s.headers,
+	}
+
+	ams, err := signer.signature(m, "ARC-Message-Signature", dkimTag{"i", strconv.Itoa(instance)})
+	if err != nil {
+		m.header.RemoveField(aar.name)
+
+		return err
+	}
+
+	tags := []dkimTag{
+		{"i", strconv.Itoa(instance)},
+		{"a", algorithm},
+		{"t", strconv.FormatInt(time.Now().Unix(), 10)},
+		{"cv", cv.String()},
+		{"d", s.domain},
+		{"s", s.selector},
+		{"b", ""},
+	}
+
+	sets = append(sets, &arcSet{aar: aarLine, ams: ams.raw, seal: foldDKIMTags("ARC-Seal", tags)})
+
+	signature, err := dkimSign(s.key, arcSealHash(sets))
+	if err != nil {
+		m.header.RemoveField(aar.name)
+
+		return err
+	}
+
+	tags[len(tags)-1].value = base64.StdEncoding.EncodeToString(signature)
+
+	m.header.prependField(ams)
+	m.header.prependField(rawField(foldDKIMTags("ARC-Seal", tags)))
+
+	return nil
+}
+
+// ARCValidator validates the ARC chains (RFC 8617) of messages.
+type ARCValidator struct {
+	resolver TXTResolver
+}
+
+// NewARCValidator is a constructor of the ARCValidator. The public keys are looked
+// up by the resolver, net.DefaultResolver is used if it is nil.
+func NewARCValidator(resolver TXTResolver) *ARCValidator {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return &ARCValidator{
+		resolver: resolver,
+	}
+}
+
+// Reset resets the value of the ARCValidator but it keeps its instance (pointer).
+func (v *ARCValidator) Reset() {
+	*v = ARCValidator{}
+}
+
+// Resolver returns the resolver of the public keys.
+func (v *ARCValidator) Resolver() TXTResolver {
+	return v.resolver
+}
+
+// SetResolver replaces the resolver of the public keys.
+func (v *ARCValidator) SetResolver(resolver TXTResolver) {
+	v.resolver = resolver
+}
+
+// Validate validates the ARC chain of the Message. The chain passes if its sets are
+// complete, the last ARC-Message-Signature matches the Message and every ARC-Seal
+// matches the sets up to its instance. The Message is read as in DKIMVerifier.Verify.
+//
+// It returns an error only if the Message can not be rendered.
+func (v *ARCValidator) Validate(ctx context.Context, m *Message) (*ARCResult, error) {
+	fields, body, err := m.wireData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate message: %w", err)
+	}
+
+	sets, err := arcSets(fields)
+	if err != nil {
+		return &ARCResult{Status: ARCFail, Err: err}, nil
+	}
+
+	if len(sets) == 0 {
+		return &ARCResult{Status: ARCNone}, nil
+	}
+
+	r := &ARCResult{Status: ARCFail, Instance: len(sets)}
+	r.Err = v.validate(ctx, sets, fields, body)
+
+	if r.Err == nil {
+		r.Status = ARCPass
+	}
+
+	return r, nil
+}
+
+// validate validates the complete ARC sets of the message.
+func (v *ARCValidator) validate(ctx context.Context, sets []*arcSet, fields []*Field, body []byte) error {
+	seals := make([]*dkimSignature, len(sets))
+
+	for i, set := range sets {
+		seal, err := parseARCSeal(set.seal)
+		if err != nil {
+			return err
+		}
+
+		want := "pass"
+		if i == 0 {
+			want = "none"
+		}
+
+		if cv := seal.tags["cv"]; cv == "fail" {
+			return fmt.Errorf("%w: instance %d", ErrARCChainFailed, i+1)
+		} else if cv != want {
+			return fmt.Errorf("%w: the chain status of instance %d is %q", ErrInvalidARCChain, i+1, cv)
+		}
+
+		seals[i] = seal
+	}
+
+	ams, err := parseDKIMSignature(sets[len(sets)-1].ams)
+	if err != nil {
+		return err
+	}
+
+	if _, err := verifyDKIMSignature(ctx, v.resolver, ams, fields, body); err != nil {
+		return fmt.Errorf("failed to verify ARC-Message-Signature of instance %d: %w", len(sets), err)
+	}
+
+	for i := len(sets) - 1; i >= 0; i-- {
+		seal := seals[i]
+
+		key, _, err := lookupDKIMKey(ctx, v.resolver, seal.selector, seal.domain)
+		if err != nil {
+			return fmt.Errorf("failed to verify ARC-Seal of instance %d: %w", i+1, err)
+		}
+
+		sealed := append(sets[:i:i], &arcSet{aar: sets[i].aar, ams: sets[i].ams, seal: stripDKIMSignature(seal.line)})
+
+		if status, err := verifyDKIMDigest(seal.algorithm, key, arcSealHash(sealed), seal.signature); status != DKIMPass {
+			if errors.Is(err, ErrDKIMSignature) {
+				err = ErrARCSeal
+			}
+
+			return fmt.Errorf("failed to verify ARC-Seal of instance %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// arcSet is an ARC set, the folded lines of its fields.
+type arcSet struct {
+	aar  string
+	ams  string
+	seal string
+}
+
+// arcSets returns the ARC sets of the fields ordered by their instances. It returns
+// ErrInvalidARCChain if a set is incomplete or an instance is duplicated or missing.
+func arcSets(fields []*Field) ([]*arcSet, error) {
+	byInstance := make(map[int]*arcSet)
+
+	for _, f := range fields {
+		name := strings.ToLower(f.name)
+		if name != "arc-authentication-results" && name != "arc-message-signature" && name != "arc-seal" {
+			continue
+		}
+
+		line, err := f.Render()
+		if err != nil {
+			return nil, err
+		}
+
+		instance, err := arcInstance(name, string(line))
+		if err != nil {
+			return nil, err
+		}
+
+		set := byInstance[instance]
+		if set == nil {
+			set = &arcSet{}
+			byInstance[instance] = set
+		}
+
+		var target *string
+
+		switch name {
+		case "arc-authentication-results":
+			target = &set.aar
+		case "arc-message-signature":
+			target = &set.ams
+		default:
+			target = &set.seal
+		}
+
+		if *target != "" {
+			return nil, fmt.Errorf("%w: duplicate %s field of instance %d", ErrInvalidARCChain, f.name, instance)
+		}
+
+		*target = string(line)
+	}
+
+	if len(byInstance) > maxARCInstances {
+		return nil, ErrARCLimit
+	}
+
+	sets := make([]*arcSet, len(byInstance))
+
+	for i := range sets {
+		set := byInstance[i+1]
+		if set == nil || set.aar == "" || set.ams == "" || set.seal == "" {
+			return nil, fmt.Errorf("%w: incomplete set of instance %d", ErrInvalidARCChain, i+1)
+		}
+
+		sets[i] = set
+	}
+
+	return sets, nil
+}
+
+// arcInstance returns the instance of the ARC field line, which is the first tag
+// of the ARC-Authentication-Results field.
+func arcInstance(name, line string) (int, error) {
+	value := line[strings.IndexByte(line, ':')+1:]
+
+	var i string
+
+	if name == "arc-authentication-results" {
+		first := strings.SplitN(value, ";", 2)[0]
+		if kv := strings.SplitN(first, "=", 2); len(kv) == 2 && trimFWS(kv[0]) == "i" {
+			i = trimFWS(kv[1])
+		}
+	} else {
+		tags, err := parseDKIMTags(value)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidARCChain, err)
+		}
+
+		i = tags["i"]
+	}
+
+	instance, err := strconv.Atoi(i)
+	if err != nil || instance < 1 || instance > maxARCInstances {
+		return 0, fmt.Errorf("%w: invalid instance %q", ErrInvalidARCChain, i)
+	}
+
+	return instance, nil
+}
+
+// parseARCSeal parses the ARC-Seal field line.
+func parseARCSeal(line string) (*dkimSignature, error) {
+	tags, err := parseDKIMTags(line[strings.IndexByte(line, ':')+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range []string{"a", "b", "cv", "d", "i", "s"} {
+		if _, ok := tags[tag]; !ok {
+			return nil, fmt.Errorf("%w: missing %s= tag of ARC-Seal", ErrInvalidDKIMSignature, tag)
+		}
+	}
+
+	if _, ok := tags["h"]; ok {
+		return nil, fmt.Errorf("%w: the ARC-Seal has an h= tag", ErrInvalidDKIMSignature)
+	}
+
+	seal := &dkimSignature{
+		line:      line,
+		tags:      tags,
+		algorithm: tags["a"],
+		domain:    tags["d"],
+		selector:  tags["s"],
+		length:    -1,
+	}
+
+	if seal.algorithm != "rsa-sha256" && seal.algorithm != "ed25519-sha256" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, seal.algorithm)
+	}
+
+	if seal.signature, err = decodeDKIMBase64(tags["b"]); err != nil {
+		return nil, err
+	}
+
+	return seal, nil
+}
+
+// arcSealHash returns the SHA-256 digest of the relaxed canonicalized ARC sets
+// ordered by their instances. The ARC-Seal of the last set is the one being signed
+// or verified, without its b= tag value and the final CRLF.
+func arcSealHash(sets []*arcSet) []byte {
+	h := sha256.New()
+
+	for i, set := range sets {
+		h.Write([]byte(canonicalField(CanonicalizationRelaxed, set.aar) + "\r\n"))
+		h.Write([]byte(canonicalField(CanonicalizationRelaxed, set.ams) + "\r\n"))
+
+		seal := canonicalField(CanonicalizationRelaxed, set.seal)
+		if i < len(sets)-1 {
+			seal += "\r\n"
+		}
+
+		h.Write([]byte(seal))
+	}
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,200 @@
+package gowl_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+// arcKeys returns a sealer of example.net and the key records of the sealers.
+func arcKeys(t *testing.T) (*gowl.ARCSealer, *gowl.ARCSealer, txtRecords) {
+	t.Helper()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	records := txtRecords{
+		"arc._domainkey.example.net":  {dkimKeyRecord(t, edKey)},
+		"list._domainkey.example.org": {dkimKeyRecord(t, rsaKey)},
+	}
+
+	return gowl.NewARCSealer("example.net", "arc", edKey), gowl.NewARCSealer("example.org", "list", rsaKey), records
+}
+
+// parsedMessage returns the dkimTestMessage as read by ParseMessage.
+func parsedMessage(t *testing.T) *gowl.Message {
+	t.Helper()
+
+	data, err := dkimTestMessage(t).Render()
+	require.NoError(t, err)
+
+	m, err := gowl.ParseMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	return m
+}
+
+func validateARC(t *testing.T, records txtRecords, m *gowl.Message) *gowl.ARCResult {
+	t.Helper()
+
+	r, err := gowl.NewARCValidator(records).Validate(context.Background(), m)
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestARCSealer_Seal(t *testing.T) {
+	t.Parallel()
+
+	mx, list, records := arcKeys(t)
+
+	m := parsedMessage(t)
+	require.Equal(t, &gowl.ARCResult{Status: gowl.ARCNone}, validateARC(t, records, m))
+
+	require.NoError(t, mx.Seal(m, "mx.example.net; dkim=none; arc=none", gowl.ARCPass))
+
+	fields := m.Header().Fields()
+	require.Equal(t, "ARC-Seal", fields[0].Name())
+	require.Equal(t, "ARC-Message-Signature", fields[1].Name())
+	require.Equal(t, "ARC-Authentication-Results", fields[2].Name())
+	require.Equal(t, []string{"i=1; mx.example.net; dkim=none; arc=none"}, fields[2].Values())
+
+	seal, err := fields[0].Render()
+	require.NoError(t, err)
+
+	tags := dkimTags(t, string(seal))
+	require.Equal(t, "1", tags["i"])
+	require.Equal(t, "none", tags["cv"])
+	require.Equal(t, "example.net", tags["d"])
+	require.NotContains(t, tags, "h")
+
+	require.Equal(t, &gowl.ARCResult{Status: gowl.ARCPass, Instance: 1}, validateARC(t, records, m))
+
+	// The list modifies the forwarded message and adds its set.
+	m.Header().Get("Subject").SetValues([]string{"[list] Quarterly report"})
+
+	chain := validateARC(t, records, m)
+	require.Equal(t, gowl.ARCFail, chain.Status)
+	require.ErrorIs(t, chain.Err, gowl.ErrDKIMSignature)
+
+	require.NoError(t, list.Seal(m, "list.example.org; arc=pass", gowl.ARCPass))
+
+	require.Equal(t, &gowl.ARCResult{Status: gowl.ARCPass, Instance: 2}, validateARC(t, records, m))
+	require.Len(t, m.Header().GetAll("ARC-Seal"), 2)
+
+	// The chain survives the transfer to the next hop.
+	data, err := m.Render()
+	require.NoError(t, err)
+
+	forwarded, err := gowl.ParseMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, &gowl.ARCResult{Status: gowl.ARCPass, Instance: 2}, validateARC(t, records, forwarded))
+
+	m.RootPart().Parts()[0].SetContent(strings.NewReader("Hello Eve"))
+
+	chain = validateARC(t, records, m)
+	require.Equal(t, gowl.ARCFail, chain.Status)
+	require.ErrorIs(t, chain.Err, gowl.ErrDKIMBodyHash)
+}
+
+func TestARCSealer_SealError(t *testing.T) {
+	t.Parallel()
+
+	mx, _, _ := arcKeys(t)
+
+	m := dkimTestMessage(t)
+	require.NoError(t, mx.Seal(m, "mx.example.net; arc=none", gowl.ARCNone))
+
+	n := len(m.Header().Fields())
+
+	err := mx.Seal(m, "mx.example.net; arc=none", gowl.ARCNone)
+	require.ErrorIs(t, err, gowl.ErrInvalidARCChain)
+	require.Len(t, m.Header().Fields(), n)
+
+	m.Header().RemoveAll("From")
+
+	err = mx.Seal(m, "mx.example.net; arc=pass", gowl.ARCPass)
+	require.ErrorIs(t, err, gowl.ErrNoFromField)
+	require.Len(t, m.Header().Fields(), n-1)
+}
+
+func TestARCValidator_Validate(t *testing.T) {
+	t.Parallel()
+
+	mx, list, records := arcKeys(t)
+
+	m := dkimTestMessage(t)
+	require.NoError(t, mx.Seal(m, "mx.example.net; arc=none", gowl.ARCNone))
+	require.NoError(t, list.Seal(m, "list.example.org; arc=pass", gowl.ARCPass))
+
+	data, err := m.Render()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		modify  func(string) string
+		wantErr error
+	}{
+		{
+			name:    "changed body",
+			modify:  func(s string) string { return strings.Replace(s, "Hello Bob", "Hello Eve", 1) },
+			wantErr: gowl.ErrDKIMBodyHash,
+		},
+		{
+			name: "changed results",
+			modify: func(s string) string {
+				return strings.Replace(s, "i=1; mx.example.net; arc=none", "i=1; mx.example.net; arc=pass", 1)
+			},
+			wantErr: gowl.ErrARCSeal,
+		},
+		{
+			name: "missing results",
+			modify: func(s string) string {
+				return strings.Replace(s, "ARC-Authentication-Results: i=1;", "X-Results: i=1;", 1)
+			},
+			wantErr: gowl.ErrInvalidARCChain,
+		},
+		{
+			name:    "duplicate instance",
+			modify:  func(s string) string { return strings.Replace(s, "i=2; list.example.org", "i=1; list.example.org", 1) },
+			wantErr: gowl.ErrInvalidARCChain,
+		},
+		{
+			name:    "failed chain",
+			modify:  func(s string) string { return strings.Replace(s, "cv=pass", "cv=fail", 1) },
+			wantErr: gowl.ErrARCChainFailed,
+		},
+		{
+			name:    "missing key",
+			modify:  func(s string) string { return strings.Replace(s, "s=list;", "s=missing;", 1) },
+			wantErr: gowl.ErrNoDKIMKey,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			modified := tt.modify(string(data))
+			require.NotEqual(t, string(data), modified)
+
+			parsed, err := gowl.ParseMessage(strings.NewReader(modified))
+			require.NoError(t, err)
+
+			r := validateARC(t, records, parsed)
+			require.Equal(t, gowl.ARCFail, r.Status)
+			require.ErrorIs(t, r.Err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package gowl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ErrInvalidSeek is returned when the content of a file attachment is seeked relative to its end.
+var ErrInvalidSeek = errors.New("the file content can be seeked only from its start or current offset")
+
+// sniffLength is the number of bytes the media type of a content is detected from.
+const sniffLength = 512
+
+// NewAttachment returns a base64 encoded attachment Part with the given filename.
+// The media type is determined by the extension of the filename, if it is unknown,
+// the media type is detected from the first bytes of the content. The filename is
+// encoded as described in RFC 2231 if it is not ASCII.
+func NewAttachment(filename string, content io.Reader) (*Part, error) {
+	return newFilePart(DispositionAttachment, filename, content)
+}
+
+// NewAttachmentFile returns an attachment Part with the content of the file at the path,
+// see NewAttachment. The file is opened only while the content is read, so no file
+// descriptor is held until the Part is rendered.
+func NewAttachmentFile(name string) (*Part, error) {
+	return newLazyFilePart(DispositionAttachment, filepath.Base(name), func() (fs.File, error) {
+		return os.Open(name)
+	})
+}
+
+// NewAttachmentFS returns an attachment Part with the content of the named file of the
+// file system, see NewAttachmentFile.
+func NewAttachmentFS(fsys fs.FS, name string) (*Part, error) {
+	return newLazyFilePart(DispositionAttachment, path.Base(name), func() (fs.File, error) {
+		return fsys.Open(name)
+	})
+}
+
+// newLazyFilePart returns a Part of the disposition with the content of the file opened on demand.
+func newLazyFilePart(disposition, filename string, open func() (fs.File, error)) (*Part, error) {
+	f, err := open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+
+	info, err := f.Stat()
+	_ = f.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat attachment: %w", err)
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("failed to open attachment: %s is a directory", filename)
+	}
+
+	p, err := newFilePart(disposition, filename, &fileContent{open: open})
+	if err != nil {
+		return nil, err
+	}
+
+	p.header.SetSize(info.Size())
+	p.header.SetModificationDate(info.ModTime())
+
+	return p, nil
+}
+
+// newFilePart returns a base64 encoded Part of the file with the disposition.
+func newFilePart(disposition, filename string, content io.Reader) (*Part, error) {
+	mediaType, params, err := mime.ParseMediaType(mime.TypeByExtension(path.Ext(filename)))
+	if err != nil {
+		var head []byte
+
+		if head, content, err = sniff(content); err != nil {
+			return nil, err
+		}
+
+		mediaType, params, _ = mime.ParseMediaType(http.DetectContentType(head))
+	}
+
+	if params == nil {
+		params = make(map[string]string)
+	}
+
+	params["name"] = filename
+
+	h := NewHeader(nil)
+	h.SetContentType(mediaType, params)
+	h.SetContentDisposition(disposition, map[string]string{"filename": filename})
+	h.setTransferEncoding(EncodingBase64)
+
+	return NewPart(h, content, nil), nil
+}
+
+// sniff returns the first bytes of the content and a reader of the whole content.
+// A content which implements io.Seeker is rewound, otherwise the bytes are prepended.
+func sniff(content io.Reader) ([]byte, io.Reader, error) {
+	head := make([]byte, sniffLength)
+
+	s, ok := content.(io.ReadSeeker)
+	if !ok {
+		n, err := io.ReadFull(content, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+
+		return head[:n], io.MultiReader(bytes.NewReader(head[:n]), content), nil
+	}
+
+	offset, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to seek attachment: %w", err)
+	}
+
+	n, err := io.ReadFull(s, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return nil, nil, fmt.Errorf("failed to seek attachment: %w", err)
+	}
+
+	return head[:n], s, nil
+}
+
+// fileContent reads a file which is opened on the first read and closed at its end.
+// It can be seeked from the start, so the Part can be rendered repeatedly.
+type fileContent struct {
+	open func() (fs.File, error)
+	f    fs.File
+	pos  int64
+}
+
+func (c *fileContent) Read(p []byte) (int, error) {
+	if c.f == nil {
+		if err := c.openAt(c.pos); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := c.f.Read(p)
+	c.pos += int64(n)
+
+	if errors.Is(err, io.EOF) {
+		_ = c.f.Close()
+		c.f = nil
+	}
+
+	return n, err
+}
+
+func (c *fileContent) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.pos
+	default:
+		return c.pos, ErrInvalidSeek
+	}
+
+	if offset < 0 {
+		return c.pos, ErrInvalidSeek
+	}
+
+	if offset == c.pos {
+		return c.pos, nil
+	}
+
+	if c.f != nil {
+		_ = c.f.Close()
+		c.f = nil
+	}
+
+	// The file is reopened at the offset on the next read.
+	c.pos = offset
+
+	return offset, nil
+}
+
+// openAt opens the file and skips to the offset.
+func (c *fileContent) openAt(offset int64) error {
+	f, err := c.open()
+	if err != nil {
+		return fmt.Errorf("failed to open attachment: %w", err)
+	}
+
+	if s, ok := f.(io.Seeker); ok {
+		_, err = s.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, f, offset)
+	}
+
+	if err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("failed to seek attachment: %w", err)
+	}
+
+	c.f = f
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package gowl_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is the signature of a PNG image.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func TestNewAttachment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		filename   string
+		content    []byte
+		seeker     bool
+		wantType   string
+		wantParams map[string]string
+	}{
+		{
+			name:       "extension",
+			filename:   "report.pdf",
+			content:    []byte("%PDF-1.4"),
+			seeker:     true,
+			wantType:   "application/pdf",
+			wantParams: map[string]string{"name": "report.pdf"},
+		},
+		{
+			name:       "sniffed seeker",
+			filename:   "logo",
+			content:    pngHeader,
+			seeker:     true,
+			wantType:   "image/png",
+			wantParams: map[string]string{"name": "logo"},
+		},
+		{
+			name:       "sniffed reader",
+			filename:   "notes",
+			content:    []byte("plain notes"),
+			wantType:   "text/plain",
+			wantParams: map[string]string{"charset": "utf-8", "name": "notes"},
+		},
+		{
+			name:       "unknown",
+			filename:   "data",
+			content:    []byte{0, 1, 2, 3},
+			wantType:   "application/octet-stream",
+			wantParams: map[string]string{"name": "data"},
+		},
+		{
+			name:       "utf-8 filename",
+			filename:   "Grüße.pdf",
+			content:    []byte("%PDF-1.4"),
+			seeker:     true,
+			wantType:   "application/pdf",
+			wantParams: map[string]string{"name": "Grüße.pdf"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var content io.Reader = bytes.NewReader(tt.content)
+			if !tt.seeker {
+				content = io.MultiReader(content)
+			}
+
+			p, err := gowl.NewAttachment(tt.filename, content)
+			require.NoError(t, err)
+
+			mediaType, params, err := p.Header().ContentType()
+			require.NoError(t, err)
+			require.Equal(t, tt.wantType, mediaType)
+			require.Equal(t, tt.wantParams, params)
+
+			disposition, _, err := p.Header().ContentDisposition()
+			require.NoError(t, err)
+			require.Equal(t, gowl.DispositionAttachment, disposition)
+
+			filename, err := p.Header().Filename()
+			require.NoError(t, err)
+			require.Equal(t, tt.filename, filename)
+
+			// The sniffed bytes are part of the rendered content.
+			rendered, err := p.Render()
+			require.NoError(t, err)
+
+			parsed, err := gowl.ParseMessage(bytes.NewReader(rendered))
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(parsed.RootPart().Content())
+			require.NoError(t, err)
+			require.Equal(t, tt.content, got)
+		})
+	}
+}
+
+func TestNewAttachmentFile(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "Grüße.pdf")
+	require.NoError(t, os.WriteFile(name, []byte("%PDF-1.4 report"), 0o600))
+
+	modified := time.Date(2021, time.March, 13, 7, 0, 30, 0, time.UTC)
+	require.NoError(t, os.Chtimes(name, modified, modified))
+
+	p, err := gowl.NewAttachmentFile(name)
+	require.NoError(t, err)
+
+	got, err := p.Render()
+	require.NoError(t, err)
+
+	content := base64.StdEncoding.EncodeToString([]byte("%PDF-1.4 report"))
+	want := "Content-Type: application/pdf; name*=UTF-8''Gr%C3%BC%C3%9Fe.pdf\r\n" +
+		"Content-Disposition: attachment; filename*=UTF-8''Gr%C3%BC%C3%9Fe.pdf;\r\n" +
+		" size=\"15\"; modification-date=\"" + modified.Local().Format("Mon, 02 Jan 2006 15:04:05 -0700") + "\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" + content
+
+	require.Equal(t, want, string(got))
+
+	// The file is reopened to render the Part again.
+	again, err := p.Render()
+	require.NoError(t, err)
+	require.Equal(t, want, string(again))
+
+	_, err = gowl.NewAttachmentFile(filepath.Join(t.TempDir(), "missing.pdf"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = gowl.NewAttachmentFile(t.TempDir())
+	require.Error(t, err)
+}
+
+func TestNewAttachmentFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"images/logo": {Data: append(pngHeader, "image data"...)},
+	}
+
+	p, err := gowl.NewAttachmentFS(fsys, "images/logo")
+	require.NoError(t, err)
+
+	mediaType, _, err := p.Header().ContentType()
+	require.NoError(t, err)
+	require.Equal(t, "image/png", mediaType)
+
+	filename, err := p.Header().Filename()
+	require.NoError(t, err)
+	require.Equal(t, "logo", filename)
+
+	size, err := p.Header().Size()
+	require.NoError(t, err)
+	require.Equal(t, int64(len(pngHeader)+10), size)
+
+	msg, err := gowl.NewMessageBuilder().
+		From("john.doe@example.com").
+		Text("See the attachment.").
+		AttachFS(fsys, "images/logo").
+		Build()
+	require.NoError(t, err)
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+
+	parsed, err := gowl.ParseMessage(bytes.NewReader(rendered))
+	require.NoError(t, err)
+	require.Len(t, parsed.RootPart().Parts(), 2)
+
+	got, err := io.ReadAll(parsed.RootPart().Parts()[1].Content())
+	require.NoError(t, err)
+	require.Equal(t, fsys["images/logo"].Data, got)
+
+	_, err = gowl.NewMessageBuilder().From("john.doe@example.com").AttachFS(fsys, "missing").Build()
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,304 @@
+package gowl
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Error codes returned by failures to authenticate with an SMTP server.
+var (
+	ErrNoAuth           = errors.New("the server does not support the AUTH extension")
+	ErrNoAuthMechanism  = errors.New("the server supports none of the given authentication mechanisms")
+	ErrInsecureAuth     = errors.New("the authentication mechanism requires an encrypted connection")
+	ErrUnexpectedServer = errors.New("the server name does not match the authentication")
+	ErrAuthChallenge    = errors.New("the server sent an unexpected authentication challenge")
+)
+
+// ServerInfo records information about an SMTP server the Auth mechanism is started with.
+type ServerInfo struct {
+	// Name is the name of the server.
+	Name string
+	// TLS reports whether the connection to the server is secured by TLS.
+	TLS bool
+	// Auth is a list of authentication mechanisms advertised by the server.
+	Auth []string
+}
+
+// Auth is implemented by an SMTP authentication mechanism (SASL).
+type Auth interface {
+	// Mechanism returns the name of the authentication mechanism
+	// the server advertises in the AUTH extension.
+	Mechanism() string
+	// Start begins an authentication with the server and returns
+	// the initial response, which might be nil.
+	Start(server *ServerInfo) ([]byte, error)
+	// Next continues the authentication. The challenge is the decoded
+	// challenge sent by the server. If more is true, the server expects
+	// a response, otherwise the challenge is the final server message.
+	Next(challenge []byte, more bool) ([]byte, error)
+}
+
+// TokenSource is a source of OAuth 2.0 bearer tokens. The token is requested
+// at the start of every authentication so the implementation can refresh
+// expired tokens between sessions.
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// TokenSourceFunc is an adapter to allow the use of an ordinary function as a TokenSource.
+type TokenSourceFunc func() (string, error)
+
+// Token calls f().
+func (f TokenSourceFunc) Token() (string, error) {
+	return f()
+}
+
+// NewStaticTokenSource returns a TokenSource which always returns the given token.
+func NewStaticTokenSource(token string) TokenSource {
+	return TokenSourceFunc(func() (string, error) {
+		return token, nil
+	})
+}
+
+// plainAuth implements the PLAIN authentication mechanism (RFC 4616).
+type plainAuth struct {
+	identity string
+	username string
+	password string
+	host     string
+}
+
+// NewPlainAuth returns an Auth that implements the PLAIN authentication
+// mechanism. It sends the credentials only over a TLS connection to the
+// server with the given host name or to localhost.
+func NewPlainAuth(identity, username, password, host string) Auth {
+	return &plainAuth{
+		identity: identity,
+		username: username,
+		password: password,
+		host:     host,
+	}
+}
+
+func (a *plainAuth) Mechanism() string {
+	return "PLAIN"
+}
+
+func (a *plainAuth) Start(server *ServerInfo) ([]byte, error) {
+	if err := checkServer(server, a.host); err != nil {
+		return nil, err
+	}
+
+	return []byte(a.identity + "\x00" + a.username + "\x00" + a.password), nil
+}
+
+func (a *plainAuth) Next(_ []byte, more bool) ([]byte, error) {
+	if more {
+		return nil, ErrAuthChallenge
+	}
+
+	return nil, nil
+}
+
+// loginAuth implements the obsolete but widely deployed LOGIN authentication mechanism.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+	step     int
+}
+
+// NewLoginAuth returns an Auth that implements the LOGIN authentication
+// mechanism. It sends the credentials only over a TLS connection to the
+// server with the given host name or to localhost.
+func NewLoginAuth(username, password, host string) Auth {
+	return &loginAuth{
+		username: username,
+		password: password,
+		host:     host,
+	}
+}
+
+func (a *loginAuth) Mechanism() string {
+	return "LOGIN"
+}
+
+func (a *loginAuth) Start(server *ServerInfo) ([]byte, error) {
+	if err := checkServer(server, a.host); err != nil {
+		return nil, err
+	}
+
+	a.step = 0
+
+	return nil, nil
+}
+
+func (a *loginAuth) Next(_ []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	a.step++
+
+	switch a.step {
+	case 1:
+		return []byte(a.username), nil
+	case 2:
+		return []byte(a.password), nil
+	default:
+		return nil, ErrAuthChallenge
+	}
+}
+
+// cramMD5Auth implements the CRAM-MD5 authentication mechanism (RFC 2195).
+type cramMD5Auth struct {
+	username string
+	secret   string
+}
+
+// NewCRAMMD5Auth returns an Auth that implements the CRAM-MD5 authentication
+// mechanism. The secret is never sent to the server.
+func NewCRAMMD5Auth(username, secret string) Auth {
+	return &cramMD5Auth{
+		username: username,
+		secret:   secret,
+	}
+}
+
+func (a *cramMD5Auth) Mechanism() string {
+	return "CRAM-MD5"
+}
+
+func (a *cramMD5Auth) Start(*ServerInfo) ([]byte, error) {
+	return nil, nil
+}
+
+func (a *cramMD5Auth) Next(challenge []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	h := hmac.New(md5.New, []byte(a.secret))
+	h.Write(challenge)
+
+	return []byte(a.username + " " + hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// xoauth2Auth implements the XOAUTH2 authentication mechanism used by Google and Microsoft.
+type xoauth2Auth struct {
+	username string
+	source   TokenSource
+}
+
+// NewXOAuth2Auth returns an Auth that implements the XOAUTH2 authentication
+// mechanism. The bearer token is requested from the source on every start.
+func NewXOAuth2Auth(username string, source TokenSource) Auth {
+	return &xoauth2Auth{
+		username: username,
+		source:   source,
+	}
+}
+
+func (a *xoauth2Auth) Mechanism() string {
+	return "XOAUTH2"
+}
+
+func (a *xoauth2Auth) Start(*ServerInfo) ([]byte, error) {
+	token, err := a.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve oauth token: %w", err)
+	}
+
+	return []byte("user=" + a.username + "\x01auth=Bearer " + token + "\x01\x01"), nil
+}
+
+func (a *xoauth2Auth) Next(_ []byte, more bool) ([]byte, error) {
+	if more {
+		// The server sends a JSON error as a challenge and expects
+		// an empty response before it fails the authentication.
+		return []byte{}, nil
+	}
+
+	return nil, nil
+}
+
+// oauthBearerAuth implements the OAUTHBEARER authentication mechanism (RFC 7628).
+type oauthBearerAuth struct {
+	username string
+	source   TokenSource
+	host     string
+	port     int
+}
+
+// NewOAuthBearerAuth returns an Auth that implements the OAUTHBEARER
+// authentication mechanism. The bearer token is requested from the source
+// on every start. The host and the port of the server are optional.
+func NewOAuthBearerAuth(username string, source TokenSource, host string, port int) Auth {
+	return &oauthBearerAuth{
+		username: username,
+		source:   source,
+		host:     host,
+		port:     port,
+	}
+}
+
+func (a *oauthBearerAuth) Mechanism() string {
+	return "OAUTHBEARER"
+}
+
+func (a *oauthBearerAuth) Start(*ServerInfo) ([]byte, error) {
+	token, err := a.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve oauth token: %w", err)
+	}
+
+	ir := "n,a=" + strings.NewReplacer("=", "=3D", ",", "=2C").Replace(a.username) + ",\x01"
+	if a.host != "" {
+		ir += "host=" + a.host + "\x01"
+	}
+
+	if a.port != 0 {
+		ir += fmt.Sprintf("port=%d\x01", a.port)
+	}
+
+	return []byte(ir + "auth=Bearer " + token + "\x01\x01"), nil
+}
+
+func (a *oauthBearerAuth) Next(_ []byte, more bool) ([]byte, error) {
+	if more {
+		// The server sends a JSON error as a challenge and expects
+		// the dummy response %x01 before it fails the authentication.
+		return []byte{0x01}, nil
+	}
+
+	return nil, nil
+}
+
+// checkServer allows sending plaintext credentials only over TLS
+// connections to the expected server or to localhost.
+func checkServer(server *ServerInfo, host string) error {
+	if host != "" && server.Name != host {
+		return ErrUnexpectedServer
+	}
+
+	if !server.TLS && !isLocalhost(server.Name) {
+		return ErrInsecureAuth
+	}
+
+	return nil
+}
+
+func isLocalhost(name string) bool {
+	if name == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(name)
+
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,335 @@
+package gowl_test
+
+import (
+	"errors"
+	"net/textproto"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+var ErrTokenExpired = errors.New("token expired")
+
+func TestAuth_Start(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		auth          gowl.Auth
+		server        *gowl.ServerInfo
+		wantMechanism string
+		want          []byte
+		wantErr       error
+	}{
+		{
+			name:          "plain",
+			auth:          gowl.NewPlainAuth("", "john", "secret", "smtp.example.com"),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com", TLS: true},
+			wantMechanism: "PLAIN",
+			want:          []byte("\x00john\x00secret"),
+		},
+		{
+			name:          "plain localhost",
+			auth:          gowl.NewPlainAuth("admin", "john", "secret", ""),
+			server:        &gowl.ServerInfo{Name: "127.0.0.1"},
+			wantMechanism: "PLAIN",
+			want:          []byte("admin\x00john\x00secret"),
+		},
+		{
+			name:          "plain insecure",
+			auth:          gowl.NewPlainAuth("", "john", "secret", "smtp.example.com"),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com"},
+			wantMechanism: "PLAIN",
+			wantErr:       gowl.ErrInsecureAuth,
+		},
+		{
+			name:          "plain unexpected server",
+			auth:          gowl.NewPlainAuth("", "john", "secret", "smtp.example.com"),
+			server:        &gowl.ServerInfo{Name: "smtp.example.org", TLS: true},
+			wantMechanism: "PLAIN",
+			wantErr:       gowl.ErrUnexpectedServer,
+		},
+		{
+			name:          "login",
+			auth:          gowl.NewLoginAuth("john", "secret", "smtp.example.com"),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com", TLS: true},
+			wantMechanism: "LOGIN",
+			want:          nil,
+		},
+		{
+			name:          "login insecure",
+			auth:          gowl.NewLoginAuth("john", "secret", "smtp.example.com"),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com"},
+			wantMechanism: "LOGIN",
+			wantErr:       gowl.ErrInsecureAuth,
+		},
+		{
+			name:          "cram-md5",
+			auth:          gowl.NewCRAMMD5Auth("john", "secret"),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com"},
+			wantMechanism: "CRAM-MD5",
+			want:          nil,
+		},
+		{
+			name:          "xoauth2",
+			auth:          gowl.NewXOAuth2Auth("john@example.com", gowl.NewStaticTokenSource("ya29.token")),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com", TLS: true},
+			wantMechanism: "XOAUTH2",
+			want:          []byte("user=john@example.com\x01auth=Bearer ya29.token\x01\x01"),
+		},
+		{
+			name: "xoauth2 token error",
+			auth: gowl.NewXOAuth2Auth("john@example.com", gowl.TokenSourceFunc(func() (string, error) {
+				return "", ErrTokenExpired
+			})),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com", TLS: true},
+			wantMechanism: "XOAUTH2",
+			wantErr:       ErrTokenExpired,
+		},
+		{
+			name:          "oauthbearer",
+			auth:          gowl.NewOAuthBearerAuth("john@example.com", gowl.NewStaticTokenSource("ya29.token"), "smtp.example.com", 587),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com", TLS: true},
+			wantMechanism: "OAUTHBEARER",
+			want:          []byte("n,a=john@example.com,\x01host=smtp.example.com\x01port=587\x01auth=Bearer ya29.token\x01\x01"),
+		},
+		{
+			name:          "oauthbearer escaped user",
+			auth:          gowl.NewOAuthBearerAuth("a=b,c", gowl.NewStaticTokenSource("t"), "", 0),
+			server:        &gowl.ServerInfo{Name: "smtp.example.com", TLS: true},
+			wantMechanism: "OAUTHBEARER",
+			want:          []byte("n,a=a=3Db=2Cc,\x01auth=Bearer t\x01\x01"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.wantMechanism, tt.auth.Mechanism())
+
+			got, err := tt.auth.Start(tt.server)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuth_Next(t *testing.T) {
+	t.Parallel()
+
+	server := &gowl.ServerInfo{Name: "localhost"}
+
+	login := gowl.NewLoginAuth("john", "secret", "")
+	_, err := login.Start(server)
+	require.NoError(t, err)
+
+	got, err := login.Next([]byte("Username:"), true)
+	require.NoError(t, err)
+	require.Equal(t, []byte("john"), got)
+
+	got, err = login.Next([]byte("Password:"), true)
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), got)
+
+	_, err = login.Next([]byte("Other:"), true)
+	require.ErrorIs(t, err, gowl.ErrAuthChallenge)
+
+	// RFC 2195 example.
+	cram := gowl.NewCRAMMD5Auth("tim", "tanstaaftanstaaf")
+	got, err = cram.Next([]byte("<1896.697170952@postoffice.reston.mci.net>"), true)
+	require.NoError(t, err)
+	require.Equal(t, []byte("tim b913a602c7eda7a495b4e6e7334d3890"), got)
+
+	plain := gowl.NewPlainAuth("", "john", "secret", "")
+	_, err = plain.Next([]byte("challenge"), true)
+	require.ErrorIs(t, err, gowl.ErrAuthChallenge)
+
+	xoauth2 := gowl.NewXOAuth2Auth("john", gowl.NewStaticTokenSource("t"))
+	got, err = xoauth2.Next([]byte(`{"status":"401"}`), true)
+	require.NoError(t, err)
+	require.Equal(t, []byte{}, got)
+
+	bearer := gowl.NewOAuthBearerAuth("john", gowl.NewStaticTokenSource("t"), "", 0)
+	got, err = bearer.Next([]byte(`{"status":"401"}`), true)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01}, got)
+}
+
+func TestClient_Auth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		extensions []string
+		secret     string
+		auths      []gowl.Auth
+		wantMech   string
+		wantErr    error
+		wantSMTP   int
+	}{
+		{
+			name:       "plain",
+			extensions: []string{"AUTH PLAIN LOGIN"},
+			secret:     "secret",
+			auths:      []gowl.Auth{gowl.NewPlainAuth("", "john", "secret", "")},
+			wantMech:   "AUTH PLAIN",
+		},
+		{
+			name:       "login",
+			extensions: []string{"AUTH PLAIN LOGIN"},
+			secret:     "secret",
+			auths:      []gowl.Auth{gowl.NewLoginAuth("john", "secret", "")},
+			wantMech:   "AUTH LOGIN",
+		},
+		{
+			name:       "login with empty password",
+			extensions: []string{"AUTH LOGIN"},
+			auths:      []gowl.Auth{gowl.NewLoginAuth("john", "", "")},
+			wantMech:   "AUTH LOGIN",
+		},
+		{
+			name:       "cram-md5 preferred",
+			extensions: []string{"AUTH PLAIN LOGIN CRAM-MD5"},
+			secret:     "secret",
+			auths: []gowl.Auth{
+				gowl.NewCRAMMD5Auth("john", "secret"),
+				gowl.NewPlainAuth("", "john", "secret", ""),
+			},
+			wantMech: "AUTH CRAM-MD5",
+		},
+		{
+			name:       "fallback to advertised",
+			extensions: []string{"AUTH LOGIN"},
+			secret:     "secret",
+			auths: []gowl.Auth{
+				gowl.NewCRAMMD5Auth("john", "secret"),
+				gowl.NewLoginAuth("john", "secret", ""),
+			},
+			wantMech: "AUTH LOGIN",
+		},
+		{
+			name:       "xoauth2",
+			extensions: []string{"AUTH XOAUTH2 OAUTHBEARER"},
+			secret:     "token",
+			auths:      []gowl.Auth{gowl.NewXOAuth2Auth("john", gowl.NewStaticTokenSource("token"))},
+			wantMech:   "AUTH XOAUTH2",
+		},
+		{
+			name:       "oauthbearer",
+			extensions: []string{"AUTH XOAUTH2 OAUTHBEARER"},
+			secret:     "token",
+			auths:      []gowl.Auth{gowl.NewOAuthBearerAuth("john", gowl.NewStaticTokenSource("token"), "127.0.0.1", 0)},
+			wantMech:   "AUTH OAUTHBEARER",
+		},
+		{
+			name:       "invalid credentials",
+			extensions: []string{"AUTH PLAIN"},
+			secret:     "secret",
+			auths:      []gowl.Auth{gowl.NewPlainAuth("", "john", "wrong", "")},
+			wantSMTP:   535,
+		},
+		{
+			// The error challenge is acknowledged with an empty line.
+			name:       "invalid token",
+			extensions: []string{"AUTH XOAUTH2"},
+			secret:     "token",
+			auths:      []gowl.Auth{gowl.NewXOAuth2Auth("john", gowl.NewStaticTokenSource("expired"))},
+			wantSMTP:   535,
+		},
+		{
+			name:       "no mechanism",
+			extensions: []string{"AUTH CRAM-MD5"},
+			secret:     "secret",
+			auths:      []gowl.Auth{gowl.NewPlainAuth("", "john", "secret", "")},
+			wantErr:    gowl.ErrNoAuthMechanism,
+		},
+		{
+			name:    "no auth extension",
+			secret:  "secret",
+			auths:   []gowl.Auth{gowl.NewPlainAuth("", "john", "secret", "")},
+			wantErr: gowl.ErrNoAuth,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newFakeServer(t, tt.extensions...)
+			s.AddUser("john", tt.secret)
+
+			c, err := gowl.Dial(s.Addr())
+			require.NoError(t, err)
+
+			defer c.Close()
+
+			err = c.Auth(tt.auths...)
+
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			case tt.wantSMTP != 0:
+				var smtpErr *textproto.Error
+				require.ErrorAs(t, err, &smtpErr)
+				require.Equal(t, tt.wantSMTP, smtpErr.Code)
+			default:
+				require.NoError(t, err)
+				require.NoError(t, c.Send("john@example.com", []string{"david.smith@example.com"}, testMessage()))
+				require.Contains(t, s.Commands()[1], tt.wantMech)
+
+				mails := s.Mails()
+				require.Len(t, mails, 1)
+				require.Equal(t, "john", mails[0].User)
+			}
+		})
+	}
+}
+
+func TestDialer_Auths(t *testing.T) {
+	t.Parallel()
+
+	s, config := newFakeTLSServer(t, false, "AUTH PLAIN XOAUTH2")
+	s.AddUser("john", "token-1")
+
+	calls := 0
+	source := gowl.TokenSourceFunc(func() (string, error) {
+		calls++
+
+		if calls > 1 {
+			s.AddUser("john", "token-2")
+
+			return "token-2", nil
+		}
+
+		return "token-1", nil
+	})
+
+	d := gowl.NewDialer(s.HostPort())
+	d.SetTLSConfig(config)
+	d.SetTLSPolicy(gowl.TLSMandatory)
+	d.SetAuths(gowl.NewXOAuth2Auth("john", source))
+	require.Len(t, d.Auths(), 1)
+
+	require.NoError(t, d.DialAndSend("john@example.com", []string{"david.smith@example.com"}, testMessage()))
+	require.NoError(t, d.DialAndSend("john@example.com", []string{"david.smith@example.com"}, testMessage()))
+	require.Equal(t, 2, calls)
+
+	mails := s.Mails()
+	require.Len(t, mails, 2)
+	require.Equal(t, "john", mails[1].User)
+	require.True(t, mails[1].Secure)
+
+	d.SetAuths(gowl.NewPlainAuth("", "john", "wrong", ""))
+	require.Error(t, d.DialAndSend("john@example.com", []string{"david.smith@example.com"}, testMessage()))
+}
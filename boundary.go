@@ -0,0 +1,207 @@
+package gowl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrBoundaryCollision is returned when a boundary of a multipart Part occurs
+// in the rendered content of its sub-parts.
+var ErrBoundaryCollision = errors.New("the boundary occurs in the content of the multipart Part")
+
+// maxBoundaryAttempts is the number of attempts to generate a boundary which
+// does not collide with the boundaries of the nested parts.
+const maxBoundaryAttempts = 10
+
+// NewBoundary generates a cryptographically random MIME boundary. The boundary
+// contains the "=_" sequence which never occurs in base64 or quoted-printable
+// encoded content.
+func NewBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate boundary: %w", err)
+	}
+
+	return "=_" + hex.EncodeToString(b), nil
+}
+
+// ensureBoundary returns the boundary of the Part. If the Part has none,
+// a random boundary which does not collide with the boundaries of the nested
+// parts is generated and stored in the Content-Type field of the Part.
+// The Content-Type field defaults to multipart/mixed if it is missing.
+func (p *Part) ensureBoundary() ([]byte, error) {
+	boundary, err := p.header.Boundary()
+	if !errors.Is(err, ErrNoBoundary) {
+		return boundary, err
+	}
+
+	if f := p.header.Get("Content-Type"); f != nil && len(f.values) == 0 {
+		return nil, ErrNoValues
+	}
+
+	b, err := p.generateBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	p.setBoundary(b)
+
+	return []byte(b), nil
+}
+
+// generateBoundary generates a random boundary which is not a prefix of any
+// boundary of the nested parts and vice versa.
+func (p *Part) generateBoundary() (string, error) {
+	nested := p.nestedBoundaries(nil)
+
+	for i := 0; i < maxBoundaryAttempts; i++ {
+		b, err := NewBoundary()
+		if err != nil {
+			return "", err
+		}
+
+		collides := false
+
+		for _, n := range nested {
+			if strings.HasPrefix(n, b) || strings.HasPrefix(b, n) {
+				collides = true
+
+				break
+			}
+		}
+
+		if !collides {
+			return b, nil
+		}
+	}
+
+	return "", ErrBoundaryCollision
+}
+
+// nestedBoundaries appends the explicit boundaries of all nested parts to bs.
+func (p *Part) nestedBoundaries(bs []string) []string {
+	for _, sub := range p.parts {
+		if b, err := sub.header.Boundary(); err == nil {
+			bs = append(bs, string(b))
+		}
+
+		bs = sub.nestedBoundaries(bs)
+	}
+
+	return bs
+}
+
+// setBoundary replaces the boundary parameter of the Content-Type field.
+func (p *Part) setBoundary(boundary string) {
+	if f := p.header.Get("Content-Type"); f != nil {
+		f.SetParam("boundary", boundary)
+
+		return
+	}
+
+	p.header.AddField(NewField("Content-Type", []string{"multipart/mixed", `boundary="` + boundary + `"`}))
+}
+
+// ValidateBoundaries verifies that the boundary of the Part and of every nested
+// multipart part does not occur in the rendered content of its sub-parts. Missing
+// boundaries are generated. It returns ErrBoundaryCollision on the first collision.
+//
+// The validation renders the sub-parts, so contents which do not implement
+// io.Seeker are read into memory to be rendered again later.
+func (p *Part) ValidateBoundaries() error {
+	return p.checkBoundaries(false)
+}
+
+// RegenerateBoundaries replaces the boundary of the Part and of every nested
+// multipart part with a random one if it occurs in the rendered content of its
+// sub-parts. Missing boundaries are generated. Like ValidateBoundaries, it reads
+// contents which do not implement io.Seeker into memory.
+func (p *Part) RegenerateBoundaries() error {
+	return p.checkBoundaries(true)
+}
+
+func (p *Part) checkBoundaries(regenerate bool) error {
+	if err := p.bufferContent(); err != nil {
+		return err
+	}
+
+	if p.parts == nil {
+		return nil
+	}
+
+	for _, sub := range p.parts {
+		if err := sub.checkBoundaries(regenerate); err != nil {
+			return err
+		}
+	}
+
+	boundary, err := p.ensureBoundary()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < maxBoundaryAttempts; i++ {
+		collides, err := p.boundaryCollides(boundary)
+		if err != nil || !collides {
+			return err
+		}
+
+		if !regenerate {
+			return fmt.Errorf("%w: %s", ErrBoundaryCollision, boundary)
+		}
+
+		b, err := p.generateBoundary()
+		if err != nil {
+			return err
+		}
+
+		p.setBoundary(b)
+		boundary = []byte(b)
+	}
+
+	return ErrBoundaryCollision
+}
+
+// boundaryCollides reports whether a line of the rendered sub-parts starts with the boundary.
+func (p *Part) boundaryCollides(boundary []byte) (bool, error) {
+	delim := append([]byte("--"), boundary...)
+
+	for _, sub := range p.parts {
+		data, err := sub.Render()
+		if err != nil {
+			return false, err
+		}
+
+		if bytes.HasPrefix(data, delim) || bytes.Contains(data, append([]byte{'\n'}, delim...)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// bufferContent reads the content of the Part into memory unless it can be
+// rewound, so the Part can be rendered repeatedly.
+func (p *Part) bufferContent() error {
+	if p.content == nil {
+		return nil
+	}
+
+	if _, ok := p.content.(io.Seeker); ok {
+		return nil
+	}
+
+	data, err := io.ReadAll(p.content)
+	if err != nil {
+		return fmt.Errorf("failed to read part content: %w", err)
+	}
+
+	p.content = bytes.NewReader(data)
+
+	return nil
+}
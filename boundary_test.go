@@ -0,0 +1,210 @@
+package gowl_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBoundary(t *testing.T) {
+	t.Parallel()
+
+	b, err := gowl.NewBoundary()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(b, "=_"))
+	require.LessOrEqual(t, len(b), 70)
+
+	b2, err := gowl.NewBoundary()
+	require.NoError(t, err)
+	require.NotEqual(t, b, b2)
+}
+
+func textParts() []*gowl.Part {
+	return []*gowl.Part{
+		gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain", `charset="UTF-8"`})}),
+			strings.NewReader("This is a test message."),
+			nil,
+		),
+		gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/html", `charset="UTF-8"`})}),
+			strings.NewReader(`<div dir="ltr">This is a test message.</div>`),
+			nil,
+		),
+	}
+}
+
+func TestPart_RenderGeneratedBoundary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		header    *gowl.Header
+		wantValue string
+	}{
+		{
+			name:      "content type without boundary",
+			header:    gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/alternative"})}),
+			wantValue: "multipart/alternative;\n ",
+		},
+		{
+			name:      "missing content type",
+			header:    gowl.NewHeader(nil),
+			wantValue: "multipart/mixed; ",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := gowl.NewPart(tt.header, nil, textParts())
+			got, err := p.Render()
+			require.NoError(t, err)
+
+			boundary, err := p.Header().Boundary()
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(string(boundary), "=_"))
+
+			want := crlf(`Content-Type: ` + tt.wantValue + `boundary="` + string(boundary) + `"
+
+--` + string(boundary) + `
+Content-Type: text/plain; charset="UTF-8"
+
+This is a test message.
+--` + string(boundary) + `
+Content-Type: text/html; charset="UTF-8"
+
+<div dir="ltr">This is a test message.</div>
+--` + string(boundary) + `--`)
+			require.Equal(t, string(want), string(got))
+
+			again, err := p.Render()
+			require.NoError(t, err)
+			require.Equal(t, string(got), string(again))
+		})
+	}
+}
+
+func TestPart_RenderNestedGeneratedBoundary(t *testing.T) {
+	t.Parallel()
+
+	inner := gowl.NewPart(gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/alternative"})}), nil, textParts())
+	outer := gowl.NewPart(gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/mixed"})}), nil, []*gowl.Part{inner})
+
+	_, err := outer.Render()
+	require.NoError(t, err)
+
+	bi, err := inner.Header().Boundary()
+	require.NoError(t, err)
+
+	bo, err := outer.Header().Boundary()
+	require.NoError(t, err)
+	require.NotEqual(t, string(bi), string(bo))
+}
+
+func TestPart_ValidateBoundaries(t *testing.T) {
+	t.Parallel()
+
+	newPart := func(boundary string, content io.Reader) *gowl.Part {
+		return gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/mixed", `boundary="` + boundary + `"`})}),
+			nil,
+			[]*gowl.Part{
+				gowl.NewPart(
+					gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}),
+					content,
+					nil,
+				),
+			},
+		)
+	}
+
+	tests := []struct {
+		name    string
+		part    *gowl.Part
+		wantErr error
+	}{
+		{
+			name: "ok",
+			part: newPart("part_12345", strings.NewReader("This is a test message.")),
+		},
+		{
+			name:    "collision in content",
+			part:    newPart("part_12345", strings.NewReader("This is a test message.\n--part_12345--\n")),
+			wantErr: gowl.ErrBoundaryCollision,
+		},
+		{
+			name:    "collision in non-seekable content",
+			part:    newPart("part", io.LimitReader(strings.NewReader("--part_12345"), 100)),
+			wantErr: gowl.ErrBoundaryCollision,
+		},
+		{
+			name:    "collision with nested boundary",
+			part:    gowl.NewPart(gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/mixed", `boundary="part"`})}), nil, []*gowl.Part{newPart("part_1", strings.NewReader("Test."))}),
+			wantErr: gowl.ErrBoundaryCollision,
+		},
+		{
+			name: "missing boundary",
+			part: gowl.NewPart(gowl.NewHeader(nil), nil, textParts()),
+		},
+		{
+			name:    "invalid content",
+			part:    newPart("part_12345", errReader{}),
+			wantErr: ErrInvalidReader,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.part.ValidateBoundaries()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			_, err = tt.part.Header().Boundary()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPart_RegenerateBoundaries(t *testing.T) {
+	t.Parallel()
+
+	content := "This is a test message.\n--part_12345--\n"
+	p := gowl.NewPart(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/mixed", `boundary="part_12345"`})}),
+		nil,
+		[]*gowl.Part{
+			gowl.NewPart(
+				gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}),
+				io.LimitReader(strings.NewReader(content), 100),
+				nil,
+			),
+		},
+	)
+
+	require.NoError(t, p.RegenerateBoundaries())
+
+	boundary, err := p.Header().Boundary()
+	require.NoError(t, err)
+	require.NotEqual(t, "part_12345", string(boundary))
+	require.Len(t, p.Header().Fields()[0].Values(), 2)
+	require.NoError(t, p.ValidateBoundaries())
+
+	got, err := p.Render()
+	require.NoError(t, err)
+	require.Contains(t, string(got), string(crlf(content)))
+}
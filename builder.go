@@ -0,0 +1,345 @@
+package gowl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// ErrNoFrom is returned when a Message is built without the From address.
+var ErrNoFrom = errors.New("the message has no From address")
+
+// Media types of the parts created by the MessageBuilder.
+const (
+	mediaTypeText        = "text/plain"
+	mediaTypeHTML        = "text/html"
+	mediaTypeMixed       = "multipart/mixed"
+	mediaTypeAlternative = "multipart/alternative"
+	mediaTypeRelated     = "multipart/related"
+)
+
+// MessageBuilder builds a Message of a common shape with the minimal MIME structure.
+// The methods can be chained, the first error of the address parsing or of the
+// attachments is returned by Build, e.g.:
+//
+//	msg, err := NewMessageBuilder().
+//		From("John Doe <john.doe@example.com>").
+//		To("david.smith@example.com").
+//		Subject("Hello").
+//		Text("Hello David!").
+//		HTML("<p>Hello David!</p>").
+//		Build()
+//
+// A text/plain or text/html body is sent as is, both of them are wrapped in
+// a multipart/alternative Part. The embedded parts are put in a multipart/related
+// Part together with the HTML body and the attachments in a multipart/mixed Part.
+type MessageBuilder struct {
+	from        []*Address
+	to          []*Address
+	cc          []*Address
+	replyTo     []*Address
+	subject     string
+	date        time.Time
+	text        *Part
+	html        *Part
+	derive      bool
+	inlineCSS   bool
+	embedded    []*Part
+	attachments []*Part
+	err         error
+}
+
+// NewMessageBuilder is a constructor of the MessageBuilder.
+func NewMessageBuilder() *MessageBuilder {
+	return &MessageBuilder{}
+}
+
+// Reset resets the value of the MessageBuilder but it keeps its instance (pointer).
+func (b *MessageBuilder) Reset() {
+	*b = MessageBuilder{}
+}
+
+// From sets the author addresses of the Message, e.g. "John Doe <john.doe@example.com>".
+func (b *MessageBuilder) From(addresses ...string) *MessageBuilder {
+	b.from = b.parseAddresses(addresses)
+
+	return b
+}
+
+// To adds the primary recipient addresses of the Message.
+func (b *MessageBuilder) To(addresses ...string) *MessageBuilder {
+	b.to = append(b.to, b.parseAddresses(addresses)...)
+
+	return b
+}
+
+// Cc adds the carbon copy recipient addresses of the Message.
+func (b *MessageBuilder) Cc(addresses ...string) *MessageBuilder {
+	b.cc = append(b.cc, b.parseAddresses(addresses)...)
+
+	return b
+}
+
+// ReplyTo sets the addresses the replies to the Message are sent to.
+func (b *MessageBuilder) ReplyTo(addresses ...string) *MessageBuilder {
+	b.replyTo = b.parseAddresses(addresses)
+
+	return b
+}
+
+// parseAddresses parses the address lists and records the first error.
+func (b *MessageBuilder) parseAddresses(lists []string) []*Address {
+	var addrs []*Address
+
+	for _, list := range lists {
+		parsed, err := ParseAddressList(list)
+		if err != nil {
+			if b.err == nil {
+				b.err = fmt.Errorf("failed to parse address %q: %w", list, err)
+			}
+
+			continue
+		}
+
+		addrs = append(addrs, parsed...)
+	}
+
+	return addrs
+}
+
+// Subject sets the subject of the Message, non-ASCII characters are encoded on rendering.
+func (b *MessageBuilder) Subject(subject string) *MessageBuilder {
+	b.subject = subject
+
+	return b
+}
+
+// Date sets the origination date of the Message. The time of Build is used by default.
+func (b *MessageBuilder) Date(date time.Time) *MessageBuilder {
+	b.date = date
+
+	return b
+}
+
+// Text sets the UTF-8 text/plain body of the Message.
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	b.text = newTextPart(mediaTypeText, strings.NewReader(text))
+
+	return b
+}
+
+// HTML sets the UTF-8 text/html body of the Message.
+func (b *MessageBuilder) HTML(html string) *MessageBuilder {
+	b.html = newTextPart(mediaTypeHTML, strings.NewReader(html))
+
+	return b
+}
+
+// TextFromHTML sets whether the text/plain body is derived from the HTML body
+// when the Message has no text body, see HTMLToText.
+func (b *MessageBuilder) TextFromHTML(derive bool) *MessageBuilder {
+	b.derive = derive
+
+	return b
+}
+
+// InlineCSS sets whether the stylesheets of the HTML body are inlined into the
+// style attributes of its elements when the Message is rendered, see Part.SetInlineCSS.
+func (b *MessageBuilder) InlineCSS(inline bool) *MessageBuilder {
+	b.inlineCSS = inline
+
+	return b
+}
+
+// Attach adds an attachment with the given filename, see NewAttachment.
+func (b *MessageBuilder) Attach(filename string, content io.Reader) *MessageBuilder {
+	return b.attach(NewAttachment(filename, content))
+}
+
+// AttachFile adds an attachment with the content of the file at the path, see NewAttachmentFile.
+func (b *MessageBuilder) AttachFile(name string) *MessageBuilder {
+	return b.attach(NewAttachmentFile(name))
+}
+
+// AttachFS adds an attachment with the content of the named file of the file system.
+func (b *MessageBuilder) AttachFS(fsys fs.FS, name string) *MessageBuilder {
+	return b.attach(NewAttachmentFS(fsys, name))
+}
+
+// attach adds the attachment or records the error of its creation.
+func (b *MessageBuilder) attach(p *Part, err error) *MessageBuilder {
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+
+		return b
+	}
+
+	b.attachments = append(b.attachments, p)
+
+	return b
+}
+
+// Embed adds an inline Part with the given filename which the HTML body references
+// by the Content-ID, e.g. <img src="cid:logo">. A Content-ID is generated if it is
+// empty, see NewInline.
+func (b *MessageBuilder) Embed(contentID, filename string, content io.Reader) *MessageBuilder {
+	p, err := NewInline(filename, content)
+
+	return b.embed(contentID, p, err)
+}
+
+// EmbedFile adds an inline Part with the content of the file at the path, see Embed.
+func (b *MessageBuilder) EmbedFile(contentID, name string) *MessageBuilder {
+	p, err := NewInlineFile(name)
+
+	return b.embed(contentID, p, err)
+}
+
+// EmbedFS adds an inline Part with the content of the named file of the file system, see Embed.
+func (b *MessageBuilder) EmbedFS(contentID string, fsys fs.FS, name string) *MessageBuilder {
+	p, err := NewInlineFS(fsys, name)
+
+	return b.embed(contentID, p, err)
+}
+
+// embed adds the inline Part with the Content-ID or records the error of its creation.
+func (b *MessageBuilder) embed(contentID string, p *Part, err error) *MessageBuilder {
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+
+		return b
+	}
+
+	if contentID != "" {
+		p.header.SetContentID(contentID)
+	}
+
+	b.embedded = append(b.embedded, p)
+
+	return b
+}
+
+// Build builds the Message. It returns ErrMissingContentID if the HTML body references
+// a Content-ID which no embedded Part has. The contents of the parts are consumed when
+// the Message is rendered, so Build should be called once per MessageBuilder.
+func (b *MessageBuilder) Build() (*Message, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if len(b.from) == 0 {
+		return nil, ErrNoFrom
+	}
+
+	date := b.date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	// The derived text is not kept, so a later Build derives it from the current HTML.
+	text := b.text
+	if b.derive && text == nil && b.html != nil {
+		var err error
+		if text, err = textFromHTML(b.html); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.html != nil {
+		b.html.SetInlineCSS(b.inlineCSS)
+	}
+
+	root := b.rootPart(text)
+	if _, err := root.ValidateContentIDs(); err != nil {
+		return nil, err
+	}
+
+	h := NewHeader(nil)
+	h.SetAddressList("From", b.from...)
+
+	if len(b.replyTo) > 0 {
+		h.SetAddressList("Reply-To", b.replyTo...)
+	}
+
+	if len(b.to) > 0 {
+		h.SetAddressList("To", b.to...)
+	}
+
+	if len(b.cc) > 0 {
+		h.SetAddressList("Cc", b.cc...)
+	}
+
+	if b.subject != "" {
+		h.AddField(NewField("Subject", []string{b.subject}))
+	}
+
+	h.AddField(NewField("Date", []string{date.Format(dateLayout)}))
+	h.AddField(NewField("MIME-Version", []string{"1.0"}))
+
+	return NewMessage(h, root), nil
+}
+
+// rootPart assembles the minimal MIME structure of the text body and the provided parts.
+func (b *MessageBuilder) rootPart(text *Part) *Part {
+	html := b.html
+	if html != nil && len(b.embedded) > 0 {
+		html = NewRelated(html, b.embedded...)
+	}
+
+	var body *Part
+
+	switch {
+	case text != nil && html != nil:
+		body = NewAlternative(text, html)
+	case html != nil:
+		body = html
+	default:
+		body = text
+	}
+
+	var parts []*Part
+
+	if body != nil {
+		parts = append(parts, body)
+	}
+
+	if b.html == nil {
+		// Without an HTML body the embedded parts can not be referenced.
+		parts = append(parts, b.embedded...)
+	}
+
+	parts = append(parts, b.attachments...)
+
+	switch len(parts) {
+	case 0:
+		return newTextPart(mediaTypeText, strings.NewReader(""))
+	case 1:
+		return parts[0]
+	default:
+		return newMultipart(mediaTypeMixed, parts)
+	}
+}
+
+// newTextPart returns a UTF-8 text Part of the media type with the automatic encoding.
+func newTextPart(mediaType string, content io.Reader) *Part {
+	h := NewHeader(nil)
+	h.SetContentType(mediaType, map[string]string{"charset": "UTF-8"})
+	h.setTransferEncoding(EncodingAuto)
+
+	return NewPart(h, content, nil)
+}
+
+// newMultipart returns a multipart Part of the media type, the boundary is generated on rendering.
+func newMultipart(mediaType string, parts []*Part) *Part {
+	h := NewHeader(nil)
+	h.SetContentType(mediaType, nil)
+
+	return NewPart(h, nil, parts)
+}
@@ -0,0 +1,168 @@
+package gowl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+// partTree describes the media types of the Part and its nested parts.
+func partTree(t *testing.T, p *gowl.Part) string {
+	t.Helper()
+
+	mediaType, _, err := p.Header().ContentType()
+	require.NoError(t, err)
+
+	if p.Parts() == nil {
+		return mediaType
+	}
+
+	subs := make([]string, 0, len(p.Parts()))
+	for _, sub := range p.Parts() {
+		subs = append(subs, partTree(t, sub))
+	}
+
+	return mediaType + "[" + strings.Join(subs, " ") + "]"
+}
+
+func TestMessageBuilder_Build(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		build func(b *gowl.MessageBuilder)
+		want  string
+	}{
+		{
+			name:  "empty",
+			build: func(b *gowl.MessageBuilder) {},
+			want:  "text/plain",
+		},
+		{
+			name:  "text",
+			build: func(b *gowl.MessageBuilder) { b.Text("Hello") },
+			want:  "text/plain",
+		},
+		{
+			name:  "html",
+			build: func(b *gowl.MessageBuilder) { b.HTML("<p>Hello</p>") },
+			want:  "text/html",
+		},
+		{
+			name:  "alternative",
+			build: func(b *gowl.MessageBuilder) { b.Text("Hello").HTML("<p>Hello</p>") },
+			want:  "multipart/alternative[text/plain text/html]",
+		},
+		{
+			name: "related",
+			build: func(b *gowl.MessageBuilder) {
+				b.HTML(`<img src="cid:logo">`).Embed("logo", "logo.png", bytes.NewReader([]byte{0x89, 'P', 'N', 'G'}))
+			},
+			want: "multipart/related[text/html image/png]",
+		},
+		{
+			name: "attachment",
+			build: func(b *gowl.MessageBuilder) {
+				b.Text("Hello").Attach("report.pdf", strings.NewReader("%PDF-1.4"))
+			},
+			want: "multipart/mixed[text/plain application/pdf]",
+		},
+		{
+			name: "attachment only",
+			build: func(b *gowl.MessageBuilder) {
+				b.Attach("report.pdf", strings.NewReader("%PDF-1.4"))
+			},
+			want: "application/pdf",
+		},
+		{
+			name: "embedded without html",
+			build: func(b *gowl.MessageBuilder) {
+				b.Text("Hello").Embed("logo", "logo.png", strings.NewReader("PNG"))
+			},
+			want: "multipart/mixed[text/plain image/png]",
+		},
+		{
+			name: "all",
+			build: func(b *gowl.MessageBuilder) {
+				b.Text("Hello").
+					HTML(`<img src="cid:logo">`).
+					Embed("logo", "logo.png", strings.NewReader("PNG")).
+					Attach("report.pdf", strings.NewReader("%PDF-1.4")).
+					Attach("notes.txt", strings.NewReader("notes"))
+			},
+			want: "multipart/mixed[multipart/alternative[text/plain multipart/related[text/html image/png]]" +
+				" application/pdf text/plain]",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := gowl.NewMessageBuilder().From("john.doe@example.com")
+			tt.build(b)
+
+			msg, err := b.Build()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, partTree(t, msg.RootPart()))
+
+			data, err := msg.Render()
+			require.NoError(t, err)
+
+			parsed, err := gowl.ParseMessage(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, partTree(t, parsed.RootPart()))
+		})
+	}
+}
+
+func TestMessageBuilder_Header(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2021, time.March, 13, 7, 0, 30, 0, time.UTC)
+
+	msg, err := gowl.NewMessageBuilder().
+		From("Zoë Müller <zoe@example.com>").
+		To("David Smith <david.smith@example.com>", "thomas.harold@example.com").
+		Cc("jane@example.com").
+		ReplyTo("support@example.com").
+		Subject("Grüße").
+		Date(date).
+		Text("Viele Grüße aus München, bis bald!").
+		Build()
+	require.NoError(t, err)
+
+	data, err := msg.Render()
+	require.NoError(t, err)
+	require.Equal(t, string(crlf(`From: =?UTF-8?b?Wm/DqyBNw7xsbGVy?= <zoe@example.com>
+Reply-To: <support@example.com>
+To: David Smith <david.smith@example.com>, <thomas.harold@example.com>
+Cc: <jane@example.com>
+Subject: =?UTF-8?b?R3LDvMOfZQ==?=
+Date: Sat, 13 Mar 2021 07:00:30 +0000
+MIME-Version: 1.0
+Content-Type: text/plain; charset="UTF-8"
+Content-Transfer-Encoding: quoted-printable
+
+Viele Gr=C3=BC=C3=9Fe aus M=C3=BCnchen, bis bald!`)), string(data))
+}
+
+func TestMessageBuilder_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := gowl.NewMessageBuilder().To("john.doe@example.com").Build()
+	require.ErrorIs(t, err, gowl.ErrNoFrom)
+
+	_, err = gowl.NewMessageBuilder().From("john.doe@example.com").To("invalid", "jane@example.com").Build()
+	require.ErrorIs(t, err, gowl.ErrInvalidAddress)
+
+	b := gowl.NewMessageBuilder().From("invalid")
+	b.Reset()
+	require.Equal(t, gowl.NewMessageBuilder(), b)
+}
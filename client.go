@@ -0,0 +1,529 @@
+package gowl
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// Error codes returned by failures of an SMTP session.
+var (
+	ErrNoSender     = errors.New("the envelope has no sender address")
+	ErrNoRecipients = errors.New("the envelope has no recipient address")
+	ErrInvalidLine  = errors.New("the SMTP command argument contains a line break")
+	ErrNoStartTLS   = errors.New("the server does not support the STARTTLS extension")
+
+	ErrSMTPUTF8Required = errors.New("the message can not be delivered without the SMTPUTF8 extension of the server")
+)
+
+// Client represents a client connection to an SMTP server.
+type Client struct {
+	conn       net.Conn
+	text       *textproto.Conn
+	serverName string
+	localName  string
+	extensions map[string]string
+	didHello   bool
+	smtputf8   bool
+}
+
+// Dial connects to the SMTP server at the given address (host:port) and returns a new Client.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial SMTP server: %w", err)
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+
+	return NewClient(conn, host)
+}
+
+// DialTLS connects to the SMTP server at the given address (host:port) over
+// an implicit TLS connection (SMTPS) and returns a new Client. If the config
+// is nil, the default configuration with the server name set to the host is used.
+func DialTLS(addr string, config *tls.Config) (*Client, error) {
+	host, _, _ := net.SplitHostPort(addr)
+
+	conn, err := tls.Dial("tcp", addr, tlsConfig(config, host))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial SMTP server: %w", err)
+	}
+
+	return NewClient(conn, host)
+}
+
+// NewClient is a constructor of the Client. It wraps an existing connection
+// to the SMTP server with the given name and reads the server greeting.
+func NewClient(conn net.Conn, serverName string) (*Client, error) {
+	text := textproto.NewConn(conn)
+
+	if _, _, err := text.ReadResponse(220); err != nil {
+		_ = text.Close()
+
+		return nil, fmt.Errorf("failed to read server greeting: %w", err)
+	}
+
+	return &Client{
+		conn:       conn,
+		text:       text,
+		serverName: serverName,
+		localName:  "localhost",
+	}, nil
+}
+
+// tlsConfig returns a copy of the config with the server name filled in.
+func tlsConfig(config *tls.Config, serverName string) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+
+	if config.ServerName == "" {
+		config.ServerName = serverName
+	}
+
+	return config
+}
+
+// ServerName returns the name of the server the Client is connected to.
+func (c *Client) ServerName() string {
+	return c.serverName
+}
+
+// Close closes the connection without sending the QUIT command.
+func (c *Client) Close() error {
+	return c.text.Close()
+}
+
+// cmd sends a command to the server and reads its response. It returns
+// an error unless the status code of the response matches expectCode.
+func (c *Client) cmd(expectCode int, format string, args ...interface{}) (int, string, error) {
+	id, err := c.text.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+
+	c.text.StartResponse(id)
+	defer c.text.EndResponse(id)
+
+	return c.text.ReadResponse(expectCode)
+}
+
+// Hello sends the EHLO command to the server using the given local name.
+// If the server does not recognize EHLO, the Client falls back to HELO.
+// Calling Hello is optional, other commands say hello on their own.
+func (c *Client) Hello(localName string) error {
+	if err := validateLine(localName); err != nil {
+		return err
+	}
+
+	c.localName = localName
+
+	return c.hello()
+}
+
+func (c *Client) hello() error {
+	if c.didHello {
+		return nil
+	}
+
+	c.didHello = true
+
+	if err := c.ehlo(); err != nil {
+		if _, _, err := c.cmd(250, "HELO %s", c.localName); err != nil {
+			return fmt.Errorf("failed to greet server: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) ehlo() error {
+	_, msg, err := c.cmd(250, "EHLO %s", c.localName)
+	if err != nil {
+		return err
+	}
+
+	c.extensions = make(map[string]string)
+
+	lines := strings.Split(msg, "\n")
+	for _, line := range lines[1:] {
+		kv := strings.SplitN(line, " ", 2)
+		if len(kv) == 2 {
+			c.extensions[strings.ToUpper(kv[0])] = kv[1]
+		} else {
+			c.extensions[strings.ToUpper(kv[0])] = ""
+		}
+	}
+
+	return nil
+}
+
+// StartTLS sends the STARTTLS command and upgrades the connection to TLS.
+// If the config is nil, the default configuration with the server name of
+// the Client is used. The Client greets the server again over the secured
+// connection as the extensions advertised before are no longer valid.
+func (c *Client) StartTLS(config *tls.Config) error {
+	ok, _, err := c.Extension("STARTTLS")
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrNoStartTLS
+	}
+
+	if _, _, err := c.cmd(220, "STARTTLS"); err != nil {
+		return fmt.Errorf("failed to start TLS: %w", err)
+	}
+
+	conn := tls.Client(c.conn, tlsConfig(config, c.serverName))
+	if err := conn.Handshake(); err != nil {
+		return fmt.Errorf("failed to perform TLS handshake: %w", err)
+	}
+
+	c.conn = conn
+	c.text = textproto.NewConn(conn)
+	c.didHello = false
+
+	return c.hello()
+}
+
+// TLSConnectionState returns the state of the TLS connection. The ok is
+// false if the connection to the server is not secured by TLS.
+func (c *Client) TLSConnectionState() (state tls.ConnectionState, ok bool) {
+	conn, ok := c.conn.(*tls.Conn)
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+
+	return conn.ConnectionState(), true
+}
+
+// Extension reports whether the server advertises the given extension
+// and returns its parameters.
+func (c *Client) Extension(ext string) (bool, string, error) {
+	if err := c.hello(); err != nil {
+		return false, "", err
+	}
+
+	param, ok := c.extensions[strings.ToUpper(ext)]
+
+	return ok, param, nil
+}
+
+// Auth authenticates the Client with the first of the given mechanisms
+// the server advertises in its AUTH extension. The order of the mechanisms
+// expresses the preference of the caller.
+func (c *Client) Auth(auths ...Auth) error {
+	ok, param, err := c.Extension("AUTH")
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrNoAuth
+	}
+
+	mechanisms := strings.Fields(strings.ToUpper(param))
+	_, isTLS := c.TLSConnectionState()
+
+	for _, a := range auths {
+		for _, m := range mechanisms {
+			if m == strings.ToUpper(a.Mechanism()) {
+				return c.auth(a, &ServerInfo{
+					Name: c.serverName,
+					TLS:  isTLS,
+					Auth: mechanisms,
+				})
+			}
+		}
+	}
+
+	return ErrNoAuthMechanism
+}
+
+func (c *Client) auth(a Auth, server *ServerInfo) error {
+	resp, err := a.Start(server)
+	if err != nil {
+		return fmt.Errorf("failed to start authentication: %w", err)
+	}
+
+	cmd := "AUTH " + a.Mechanism()
+	if resp != nil {
+		cmd += " " + encodeInitialResponse(resp)
+	}
+
+	code, msg, err := c.cmd(0, "%s", cmd)
+
+	for err == nil && code == 334 {
+		var challenge []byte
+
+		challenge, err = base64.StdEncoding.DecodeString(msg)
+		if err != nil {
+			break
+		}
+
+		resp, err = a.Next(challenge, true)
+		if err != nil {
+			break
+		}
+
+		// An empty response to a challenge is an empty line.
+		code, msg, err = c.cmd(0, "%s", base64.StdEncoding.EncodeToString(resp))
+	}
+
+	if err != nil {
+		// Cancel the exchange, the server answers with an error.
+		_, _, _ = c.cmd(501, "*")
+
+		return fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	if code != 235 {
+		return fmt.Errorf("failed to authenticate: %w", &textproto.Error{Code: code, Msg: msg})
+	}
+
+	if _, err := a.Next([]byte(msg), false); err != nil {
+		return fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	return nil
+}
+
+// encodeInitialResponse encodes the initial response of the AUTH command. An empty
+// initial response is encoded as "=" to distinguish it from none (RFC 4954).
+func encodeInitialResponse(resp []byte) string {
+	if len(resp) == 0 {
+		return "="
+	}
+
+	return base64.StdEncoding.EncodeToString(resp)
+}
+
+// Mail sends the MAIL FROM command with the given sender address. If the
+// address is internationalized and the server supports the SMTPUTF8 extension,
+// the transaction is started with the SMTPUTF8 parameter. Otherwise the domain
+// of the address is converted to punycode and ErrSMTPUTF8Required is returned
+// if the local part is not ASCII.
+func (c *Client) Mail(from string) error {
+	return c.mail(from, !isASCII(from))
+}
+
+// mail starts the mail transaction, requesting SMTPUTF8 if utf8 is true and the
+// server supports it.
+func (c *Client) mail(from string, utf8 bool) error {
+	if err := validateLine(from); err != nil {
+		return err
+	}
+
+	if err := c.hello(); err != nil {
+		return err
+	}
+
+	c.smtputf8 = false
+
+	if utf8 {
+		if _, ok := c.extensions["SMTPUTF8"]; ok {
+			c.smtputf8 = true
+		}
+	}
+
+	cmd := "MAIL FROM:<%s>"
+	if c.smtputf8 {
+		cmd += " SMTPUTF8"
+	} else {
+		var err error
+		if from, err = envelopeASCII(from); err != nil {
+			return err
+		}
+	}
+
+	if _, _, err := c.cmd(250, cmd, from); err != nil {
+		return fmt.Errorf("failed to set envelope sender: %w", err)
+	}
+
+	return nil
+}
+
+// Rcpt sends the RCPT TO command with the given recipient address. Unless the
+// transaction was started with the SMTPUTF8 parameter, the domain of the address
+// is converted to punycode and ErrSMTPUTF8Required is returned if the local part
+// is not ASCII.
+func (c *Client) Rcpt(to string) error {
+	if err := validateLine(to); err != nil {
+		return err
+	}
+
+	if !c.smtputf8 {
+		var err error
+		if to, err = envelopeASCII(to); err != nil {
+			return err
+		}
+	}
+
+	if _, _, err := c.cmd(25, "RCPT TO:<%s>", to); err != nil {
+		return fmt.Errorf("failed to add envelope recipient: %w", err)
+	}
+
+	return nil
+}
+
+// envelopeASCII converts the envelope address to ASCII for a server without SMTPUTF8.
+func envelopeASCII(addr string) (string, error) {
+	ascii, err := AddressToASCII(addr)
+	if errors.Is(err, ErrNonASCIILocalPart) {
+		return "", fmt.Errorf("%w: %v", ErrSMTPUTF8Required, err)
+	}
+
+	return ascii, err
+}
+
+// Data sends the DATA command and returns a writer of the message content.
+// The content is dot-stuffed and the caller must close the writer to finish
+// the transaction.
+func (c *Client) Data() (io.WriteCloser, error) {
+	if _, _, err := c.cmd(354, "DATA"); err != nil {
+		return nil, fmt.Errorf("failed to start message data: %w", err)
+	}
+
+	return &dataWriter{c: c, w: c.text.DotWriter()}, nil
+}
+
+// dataWriter writes the message content and reads the server response on close.
+type dataWriter struct {
+	c *Client
+	w io.WriteCloser
+}
+
+func (d *dataWriter) Write(p []byte) (int, error) {
+	return d.w.Write(p)
+}
+
+func (d *dataWriter) Close() error {
+	if err := d.w.Close(); err != nil {
+		return err
+	}
+
+	if _, _, err := d.c.text.ReadResponse(250); err != nil {
+		return fmt.Errorf("failed to finish message data: %w", err)
+	}
+
+	return nil
+}
+
+// Send sends the Message to the given recipients in a single mail transaction.
+// The SMTPUTF8 extension is requested if any address or the Message header is
+// internationalized, see Mail for the conversion of the addresses otherwise.
+// The Message is streamed to the server as it is rendered. If the rendering
+// fails in the middle of the data, the connection is closed so the server
+// discards the incomplete message. If the server rejects the sender, a
+// recipient or the data command, the mail transaction is reset so the
+// Client can be used for the next Send.
+func (c *Client) Send(from string, to []string, msg *Message) error {
+	if from == "" {
+		return ErrNoSender
+	}
+
+	if len(to) == 0 {
+		return ErrNoRecipients
+	}
+
+	header, err := msg.Header().Render()
+	if err != nil {
+		return fmt.Errorf("failed to render message header: %w", err)
+	}
+
+	utf8 := !isASCII(string(header))
+	if utf8 {
+		// Unlike the envelope addresses, the header can not be converted.
+		ok, _, err := c.Extension("SMTPUTF8")
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return ErrSMTPUTF8Required
+		}
+	}
+
+	utf8 = utf8 || !isASCII(from)
+	for _, rcpt := range to {
+		utf8 = utf8 || !isASCII(rcpt)
+	}
+
+	if err := c.mail(from, utf8); err != nil {
+		return c.reset(err)
+	}
+
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return c.reset(err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return c.reset(err)
+	}
+
+	if _, err := msg.WriteTo(w); err != nil {
+		_ = c.Close()
+
+		return fmt.Errorf("failed to write message data: %w", err)
+	}
+
+	return w.Close()
+}
+
+// reset aborts the current mail transaction with the RSET command and
+// returns the error that caused it. A failure of the RSET command itself
+// is ignored, the next command reports the state of the connection.
+func (c *Client) reset(err error) error {
+	_, _, _ = c.cmd(250, "RSET")
+
+	return err
+}
+
+// Noop sends the NOOP command to the server. It is useful to check
+// whether the connection is still alive.
+func (c *Client) Noop() error {
+	if err := c.hello(); err != nil {
+		return err
+	}
+
+	if _, _, err := c.cmd(250, "NOOP"); err != nil {
+		return fmt.Errorf("failed to send noop: %w", err)
+	}
+
+	return nil
+}
+
+// Quit sends the QUIT command and closes the connection to the server.
+func (c *Client) Quit() error {
+	if err := c.hello(); err != nil {
+		return err
+	}
+
+	if _, _, err := c.cmd(221, "QUIT"); err != nil {
+		return fmt.Errorf("failed to quit session: %w", err)
+	}
+
+	return c.Close()
+}
+
+// validateLine checks that the given command argument contains no line breaks.
+func validateLine(line string) error {
+	if strings.ContainsAny(line, "\r\n") {
+		return ErrInvalidLine
+	}
+
+	return nil
+}
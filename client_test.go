@@ -0,0 +1,404 @@
+package gowl_test
+
+import (
+	"io"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func testMessage() *gowl.Message {
+	return gowl.NewMessage(
+		gowl.NewHeader([]*gowl.Field{
+			gowl.NewField("From", []string{"John Doe <john.doe@example.com>"}),
+			gowl.NewField("To", []string{"David Smith <david.smith@example.com>"}),
+			gowl.NewField("Subject", []string{"Hello"}),
+		}),
+		gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain", `charset="UTF-8"`})}),
+			strings.NewReader("This is a test message.\n.Dot-leading line."),
+			nil,
+		),
+	)
+}
+
+func TestDial(t *testing.T) {
+	t.Parallel()
+
+	s := newFakeServer(t)
+
+	c, err := gowl.Dial(s.Addr())
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", c.ServerName())
+	require.NoError(t, c.Quit())
+
+	_, err = gowl.Dial("127.0.0.1:1")
+	require.Error(t, err)
+}
+
+func TestClient_Hello(t *testing.T) {
+	t.Parallel()
+
+	s := newFakeServer(t, "PIPELINING", "SIZE 35882577", "8BITMIME")
+
+	c, err := gowl.Dial(s.Addr())
+	require.NoError(t, err)
+
+	require.ErrorIs(t, c.Hello("bad\r\nname"), gowl.ErrInvalidLine)
+	require.NoError(t, c.Hello("client.example.com"))
+
+	ok, param, err := c.Extension("size")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "35882577", param)
+
+	ok, _, err = c.Extension("STARTTLS")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, c.Quit())
+	require.Equal(t, []string{"EHLO client.example.com", "QUIT"}, s.Commands())
+}
+
+func TestClient_HelloFallback(t *testing.T) {
+	t.Parallel()
+
+	s := newFakeServer(t)
+	s.noEHLO = true
+
+	c, err := gowl.Dial(s.Addr())
+	require.NoError(t, err)
+
+	require.NoError(t, c.Noop())
+	require.NoError(t, c.Quit())
+	require.Equal(t, []string{"EHLO localhost", "HELO localhost", "NOOP", "QUIT"}, s.Commands())
+}
+
+func TestClient_Send(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		from string
+		to   []string
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		wantErr  error
+		wantSMTP int
+	}{
+		{
+			name: "ok",
+			args: args{
+				from: "john.doe@example.com",
+				to:   []string{"david.smith@example.com", "thomas.harold@example.com"},
+			},
+		},
+		{
+			name: "no sender",
+			args: args{
+				to: []string{"david.smith@example.com"},
+			},
+			wantErr: gowl.ErrNoSender,
+		},
+		{
+			name: "no recipients",
+			args: args{
+				from: "john.doe@example.com",
+			},
+			wantErr: gowl.ErrNoRecipients,
+		},
+		{
+			name: "invalid recipient",
+			args: args{
+				from: "john.doe@example.com",
+				to:   []string{"david.smith@example.com\r\nDATA"},
+			},
+			wantErr: gowl.ErrInvalidLine,
+		},
+		{
+			name: "rejected recipient",
+			args: args{
+				from: "john.doe@example.com",
+				to:   []string{"reject@example.com"},
+			},
+			wantSMTP: 550,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newFakeServer(t)
+
+			c, err := gowl.Dial(s.Addr())
+			require.NoError(t, err)
+
+			defer c.Close()
+
+			err = c.Send(tt.args.from, tt.args.to, testMessage())
+
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Empty(t, s.Mails())
+			case tt.wantSMTP != 0:
+				var smtpErr *textproto.Error
+				require.ErrorAs(t, err, &smtpErr)
+				require.Equal(t, tt.wantSMTP, smtpErr.Code)
+				require.Empty(t, s.Mails())
+			default:
+				require.NoError(t, err)
+				require.NoError(t, c.Quit())
+
+				want, err := testMessage().Render()
+				require.NoError(t, err)
+
+				mails := s.Mails()
+				require.Len(t, mails, 1)
+				require.Equal(t, tt.args.from, mails[0].From)
+				require.Equal(t, tt.args.to, mails[0].To)
+				// The server reads the data with LF line breaks.
+				require.Equal(t, strings.ReplaceAll(string(want), "\r\n", "\n")+"\n", mails[0].Data)
+			}
+		})
+	}
+}
+
+func TestClient_SendAfterRejection(t *testing.T) {
+	t.Parallel()
+
+	s := newFakeServer(t)
+
+	c, err := gowl.Dial(s.Addr())
+	require.NoError(t, err)
+
+	defer c.Close()
+
+	var smtpErr *textproto.Error
+
+	err = c.Send("john.doe@example.com", []string{"david.smith@example.com", "reject@example.com"}, testMessage())
+	require.ErrorAs(t, err, &smtpErr)
+	require.Equal(t, 550, smtpErr.Code)
+
+	require.NoError(t, c.Send("john.doe@example.com", []string{"thomas.harold@example.com"}, testMessage()))
+	require.NoError(t, c.Quit())
+
+	mails := s.Mails()
+	require.Len(t, mails, 1)
+	require.Equal(t, []string{"thomas.harold@example.com"}, mails[0].To)
+	require.Contains(t, s.Commands(), "RSET")
+}
+
+func TestClient_StartTLS(t *testing.T) {
+	t.Parallel()
+
+	s, config := newFakeTLSServer(t, false, "8BITMIME")
+
+	c, err := gowl.Dial(s.Addr())
+	require.NoError(t, err)
+
+	defer c.Close()
+
+	_, ok := c.TLSConnectionState()
+	require.False(t, ok)
+
+	require.NoError(t, c.StartTLS(config))
+
+	state, ok := c.TLSConnectionState()
+	require.True(t, ok)
+	require.True(t, state.HandshakeComplete)
+
+	ok, _, err = c.Extension("STARTTLS")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.ErrorIs(t, c.StartTLS(config), gowl.ErrNoStartTLS)
+
+	require.NoError(t, c.Send("john.doe@example.com", []string{"david.smith@example.com"}, testMessage()))
+	require.NoError(t, c.Quit())
+
+	mails := s.Mails()
+	require.Len(t, mails, 1)
+	require.True(t, mails[0].Secure)
+	require.Equal(t, []string{"EHLO localhost", "STARTTLS", "EHLO localhost"}, s.Commands()[:3])
+}
+
+func TestClient_StartTLSUntrusted(t *testing.T) {
+	t.Parallel()
+
+	s, _ := newFakeTLSServer(t, false)
+
+	c, err := gowl.Dial(s.Addr())
+	require.NoError(t, err)
+
+	defer c.Close()
+
+	require.Error(t, c.StartTLS(nil))
+}
+
+func TestDialTLS(t *testing.T) {
+	t.Parallel()
+
+	s, config := newFakeTLSServer(t, true)
+
+	c, err := gowl.DialTLS(s.Addr(), config)
+	require.NoError(t, err)
+
+	_, ok := c.TLSConnectionState()
+	require.True(t, ok)
+	require.NoError(t, c.Send("john.doe@example.com", []string{"david.smith@example.com"}, testMessage()))
+	require.NoError(t, c.Quit())
+
+	mails := s.Mails()
+	require.Len(t, mails, 1)
+	require.True(t, mails[0].Secure)
+
+	_, err = gowl.DialTLS(s.Addr(), nil)
+	require.Error(t, err)
+}
+
+func TestClient_SendStream(t *testing.T) {
+	t.Parallel()
+
+	s := newFakeServer(t)
+
+	c, err := gowl.Dial(s.Addr())
+	require.NoError(t, err)
+
+	large := gowl.NewMessage(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Subject", []string{"Large"})}),
+		gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}),
+			io.LimitReader(strings.NewReader(strings.Repeat("a", 1<<20)), 1<<20),
+			nil,
+		),
+	)
+	require.NoError(t, c.Send("john.doe@example.com", []string{"david.smith@example.com"}, large))
+
+	broken := gowl.NewMessage(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Subject", []string{"Broken"})}),
+		gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}),
+			errReader{},
+			nil,
+		),
+	)
+	require.ErrorIs(t, c.Send("john.doe@example.com", []string{"david.smith@example.com"}, broken), ErrInvalidReader)
+	require.Error(t, c.Noop())
+
+	mails := s.Mails()
+	require.Len(t, mails, 1)
+	require.Len(t, mails[0].Data, len("Subject: Large\nContent-Type: text/plain\n\n")+1<<20+1)
+}
+
+func TestClient_SendSMTPUTF8(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		extensions []string
+		from       string
+		to         []string
+		header     string
+		wantErr    error
+		wantFrom   string
+		wantTo     []string
+		wantUTF8   bool
+	}{
+		{
+			name:       "utf-8 local part",
+			extensions: []string{"SMTPUTF8"},
+			from:       "用户@例子.中国",
+			to:         []string{"david.smith@example.com"},
+			wantFrom:   "用户@例子.中国",
+			wantTo:     []string{"david.smith@example.com"},
+			wantUTF8:   true,
+		},
+		{
+			name:       "utf-8 header",
+			extensions: []string{"SMTPUTF8"},
+			from:       "john.doe@example.com",
+			to:         []string{"david.smith@example.com"},
+			header:     "用户 <用户@例子.中国>",
+			wantFrom:   "john.doe@example.com",
+			wantTo:     []string{"david.smith@example.com"},
+			wantUTF8:   true,
+		},
+		{
+			name:     "punycode domains",
+			from:     "john.doe@例子.中国",
+			to:       []string{"max@München.de", "david.smith@example.com"},
+			wantFrom: "john.doe@xn--fsqu00a.xn--fiqs8s",
+			wantTo:   []string{"max@xn--mnchen-3ya.de", "david.smith@example.com"},
+		},
+		{
+			name:       "ascii",
+			extensions: []string{"SMTPUTF8"},
+			from:       "john.doe@example.com",
+			to:         []string{"david.smith@example.com"},
+			wantFrom:   "john.doe@example.com",
+			wantTo:     []string{"david.smith@example.com"},
+		},
+		{
+			name:    "utf-8 local part without extension",
+			from:    "john.doe@example.com",
+			to:      []string{"用户@例子.中国"},
+			wantErr: gowl.ErrSMTPUTF8Required,
+		},
+		{
+			name:    "utf-8 header without extension",
+			from:    "john.doe@example.com",
+			to:      []string{"david.smith@example.com"},
+			header:  "用户 <用户@例子.中国>",
+			wantErr: gowl.ErrSMTPUTF8Required,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newFakeServer(t, tt.extensions...)
+
+			c, err := gowl.Dial(s.Addr())
+			require.NoError(t, err)
+
+			defer c.Close()
+
+			msg := testMessage()
+			if tt.header != "" {
+				msg.Header().Set("From", []string{tt.header})
+			}
+
+			err = c.Send(tt.from, tt.to, msg)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Empty(t, s.Mails())
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, c.Quit())
+
+			mails := s.Mails()
+			require.Len(t, mails, 1)
+			require.Equal(t, tt.wantFrom, mails[0].From)
+			require.Equal(t, tt.wantTo, mails[0].To)
+			require.Equal(t, tt.wantUTF8, mails[0].UTF8)
+
+			if tt.header != "" {
+				require.Contains(t, mails[0].Data, "From: =?UTF-8?b?55So5oi3?= <用户@例子.中国>\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package gowl
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes returned by failures to read the Content-Type and Content-Disposition fields.
+var (
+	ErrNoContentType        = errors.New("the Header has no Content-Type field")
+	ErrNoContentDisposition = errors.New("the Header has no Content-Disposition field")
+	ErrInvalidMediaType     = errors.New("the media type is not in the type/subtype form")
+	ErrNoParam              = errors.New("the field has no such parameter")
+)
+
+// Dispositions of a Part defined by RFC 2183.
+const (
+	DispositionInline     = "inline"
+	DispositionAttachment = "attachment"
+)
+
+// dateLayout is the RFC 5322 date-time layout of the Content-Disposition dates.
+const dateLayout = "Mon, 02 Jan 2006 15:04:05 -0700"
+
+// setField replaces all fields with the given name by a field with the values and returns it.
+func (h *Header) setField(name string, values []string) *Field {
+	h.Set(name, values)
+
+	return h.Get(name)
+}
+
+// ContentType returns the lower-cased media type of the Content-Type field and
+// its decoded parameters keyed by their lower-cased names.
+func (h *Header) ContentType() (string, map[string]string, error) {
+	f := h.Get("Content-Type")
+	if f == nil {
+		return "", nil, ErrNoContentType
+	}
+
+	mediaType, err := f.firstValue()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if i := strings.IndexByte(mediaType, '/'); i <= 0 || i == len(mediaType)-1 || strings.Contains(mediaType, "=") {
+		return "", nil, fmt.Errorf("%w: %q", ErrInvalidMediaType, mediaType)
+	}
+
+	return mediaType, f.Params(), nil
+}
+
+// SetContentType replaces the Content-Type field with the media type and its
+// parameters, e.g. SetContentType("text/plain", map[string]string{"charset": "UTF-8"}).
+// The parameters are sorted by their names and encoded as described in Field.SetParam.
+func (h *Header) SetContentType(mediaType string, params map[string]string) {
+	setParams(h.setField("Content-Type", []string{mediaType}), params)
+}
+
+// ContentDisposition returns the lower-cased disposition type of the
+// Content-Disposition field and its decoded parameters keyed by their
+// lower-cased names.
+func (h *Header) ContentDisposition() (string, map[string]string, error) {
+	f := h.Get("Content-Disposition")
+	if f == nil {
+		return "", nil, ErrNoContentDisposition
+	}
+
+	disposition, err := f.firstValue()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return disposition, f.Params(), nil
+}
+
+// firstValue returns the lower-cased value which precedes the parameters of the Field.
+func (f *Field) firstValue() (string, error) {
+	if len(f.values) == 0 {
+		return "", ErrNoValues
+	}
+
+	values := splitParams(f.values[0])
+	if len(values) == 0 {
+		return "", ErrNoValues
+	}
+
+	return strings.ToLower(values[0]), nil
+}
+
+// SetContentDisposition replaces the Content-Disposition field with the
+// disposition type, e.g. DispositionAttachment, and its parameters.
+func (h *Header) SetContentDisposition(disposition string, params map[string]string) {
+	setParams(h.setField("Content-Disposition", []string{disposition}), params)
+}
+
+// setParams sets the parameters of the field in the order of their names.
+func setParams(f *Field, params map[string]string) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		f.SetParam(name, params[name])
+	}
+}
+
+// dispositionParam returns the parameter of the Content-Disposition field.
+func (h *Header) dispositionParam(param string) (string, error) {
+	_, params, err := h.ContentDisposition()
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := params[param]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNoParam, param)
+	}
+
+	return v, nil
+}
+
+// setDispositionParam sets the parameter of the Content-Disposition field, the
+// field is added with the attachment disposition if the Header has none.
+func (h *Header) setDispositionParam(param, value string) {
+	f := h.Get("Content-Disposition")
+	if f == nil || len(f.values) == 0 {
+		f = h.setField("Content-Disposition", []string{DispositionAttachment})
+	}
+
+	f.SetParam(param, value)
+}
+
+// Filename returns the filename parameter of the Content-Disposition field.
+// If it is missing, the name parameter of the Content-Type field is returned.
+func (h *Header) Filename() (string, error) {
+	filename, err := h.dispositionParam("filename")
+	if err == nil {
+		return filename, nil
+	}
+
+	if _, params, ctErr := h.ContentType(); ctErr == nil {
+		if name, ok := params["name"]; ok {
+			return name, nil
+		}
+	}
+
+	return "", err
+}
+
+// SetFilename sets the filename parameter of the Content-Disposition field.
+func (h *Header) SetFilename(filename string) {
+	h.setDispositionParam("filename", filename)
+}
+
+// Size returns the size parameter of the Content-Disposition field in bytes.
+func (h *Header) Size() (int64, error) {
+	v, err := h.dispositionParam("size")
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse size parameter: %w", err)
+	}
+
+	return size, nil
+}
+
+// SetSize sets the size parameter of the Content-Disposition field in bytes.
+func (h *Header) SetSize(size int64) {
+	h.setDispositionParam("size", strconv.FormatInt(size, 10))
+}
+
+// CreationDate returns the creation-date parameter of the Content-Disposition field.
+func (h *Header) CreationDate() (time.Time, error) {
+	return h.dispositionDate("creation-date")
+}
+
+// SetCreationDate sets the creation-date parameter of the Content-Disposition field.
+func (h *Header) SetCreationDate(date time.Time) {
+	h.setDispositionParam("creation-date", date.Format(dateLayout))
+}
+
+// ModificationDate returns the modification-date parameter of the Content-Disposition field.
+func (h *Header) ModificationDate() (time.Time, error) {
+	return h.dispositionDate("modification-date")
+}
+
+// SetModificationDate sets the modification-date parameter of the Content-Disposition field.
+func (h *Header) SetModificationDate(date time.Time) {
+	h.setDispositionParam("modification-date", date.Format(dateLayout))
+}
+
+func (h *Header) dispositionDate(param string) (time.Time, error) {
+	v, err := h.dispositionParam(param)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	date, err := mail.ParseDate(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse %s parameter: %w", param, err)
+	}
+
+	return date, nil
+}
@@ -0,0 +1,153 @@
+package gowl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeader_ContentType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		fields     []*gowl.Field
+		wantType   string
+		wantParams map[string]string
+		wantErr    error
+	}{
+		{
+			name:       "ok",
+			fields:     []*gowl.Field{gowl.NewField("content-type", []string{"Text/Plain", `charset="UTF-8"`})},
+			wantType:   "text/plain",
+			wantParams: map[string]string{"charset": "UTF-8"},
+		},
+		{
+			name:       "joined values",
+			fields:     []*gowl.Field{gowl.NewField("Content-Type", []string{`multipart/mixed; boundary="part_1"`})},
+			wantType:   "multipart/mixed",
+			wantParams: map[string]string{"boundary": "part_1"},
+		},
+		{
+			name:    "missing",
+			fields:  []*gowl.Field{gowl.NewField("Subject", []string{"Test"})},
+			wantErr: gowl.ErrNoContentType,
+		},
+		{
+			name:    "no values",
+			fields:  []*gowl.Field{gowl.NewField("Content-Type", nil)},
+			wantErr: gowl.ErrNoValues,
+		},
+		{
+			name:    "invalid",
+			fields:  []*gowl.Field{gowl.NewField("Content-Type", []string{"text"})},
+			wantErr: gowl.ErrInvalidMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mediaType, params, err := gowl.NewHeader(tt.fields).ContentType()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.wantType, mediaType)
+			require.Equal(t, tt.wantParams, params)
+		})
+	}
+}
+
+func TestHeader_SetContentType(t *testing.T) {
+	t.Parallel()
+
+	h := gowl.NewHeader([]*gowl.Field{
+		gowl.NewField("Subject", []string{"Test"}),
+		gowl.NewField("Content-Type", []string{"text/html"}),
+	})
+	h.SetContentType("text/plain", map[string]string{"format": "flowed", "charset": "UTF-8"})
+
+	got, err := h.Render()
+	require.NoError(t, err)
+	require.Equal(t, "Subject: Test\r\nContent-Type: text/plain; charset=\"UTF-8\"; format=\"flowed\"", string(got))
+
+	h = gowl.NewHeader(nil)
+	h.SetContentType("application/pdf", map[string]string{"name": "Grüße.pdf"})
+
+	mediaType, params, err := h.ContentType()
+	require.NoError(t, err)
+	require.Equal(t, "application/pdf", mediaType)
+	require.Equal(t, map[string]string{"name": "Grüße.pdf"}, params)
+
+	filename, err := h.Filename()
+	require.NoError(t, err)
+	require.Equal(t, "Grüße.pdf", filename)
+}
+
+func TestHeader_ContentDisposition(t *testing.T) {
+	t.Parallel()
+
+	h := gowl.NewHeader(nil)
+
+	_, _, err := h.ContentDisposition()
+	require.ErrorIs(t, err, gowl.ErrNoContentDisposition)
+
+	_, err = h.Filename()
+	require.ErrorIs(t, err, gowl.ErrNoContentDisposition)
+
+	created := time.Date(2021, time.March, 13, 7, 0, 30, 0, time.FixedZone("PST", -8*60*60))
+	modified := created.Add(time.Hour)
+
+	h.SetFilename("report.pdf")
+	h.SetSize(1024)
+	h.SetCreationDate(created)
+	h.SetModificationDate(modified)
+
+	got, err := h.Render()
+	require.NoError(t, err)
+	require.Equal(t, "Content-Disposition: attachment; filename=\"report.pdf\"; size=\"1024\";\r\n"+
+		" creation-date=\"Sat, 13 Mar 2021 07:00:30 -0800\";\r\n"+
+		" modification-date=\"Sat, 13 Mar 2021 08:00:30 -0800\"", string(got))
+
+	disposition, params, err := h.ContentDisposition()
+	require.NoError(t, err)
+	require.Equal(t, gowl.DispositionAttachment, disposition)
+	require.Len(t, params, 4)
+
+	filename, err := h.Filename()
+	require.NoError(t, err)
+	require.Equal(t, "report.pdf", filename)
+
+	size, err := h.Size()
+	require.NoError(t, err)
+	require.Equal(t, int64(1024), size)
+
+	date, err := h.CreationDate()
+	require.NoError(t, err)
+	require.True(t, created.Equal(date))
+
+	date, err = h.ModificationDate()
+	require.NoError(t, err)
+	require.True(t, modified.Equal(date))
+
+	h.SetContentDisposition(gowl.DispositionInline, map[string]string{"size": "large"})
+
+	disposition, _, err = h.ContentDisposition()
+	require.NoError(t, err)
+	require.Equal(t, gowl.DispositionInline, disposition)
+
+	_, err = h.Size()
+	require.Error(t, err)
+
+	_, err = h.CreationDate()
+	require.ErrorIs(t, err, gowl.ErrNoParam)
+}
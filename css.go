@@ -0,0 +1,883 @@
+package gowl
+
+import (
+	"sort"
+	"strings"
+)
+
+// voidElements are the HTML elements which have no end tag.
+var voidElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"param":  true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+// InlineCSS moves the rules of the <style> elements of the HTML document into the
+// style attributes of the elements they match, as many mail clients ignore the
+// <style> elements. The declarations are applied in the order of the cascade:
+// the !important declarations win over the others, then the declarations of the
+// style attributes and then the rules of higher selector specificity or of later
+// position. The rules which can not be inlined, e.g. media queries, @font-face or
+// rules with pseudo-classes such as :hover, are retained in a <style> element.
+// The <style> elements with a media attribute other than "all" or "screen" are
+// left untouched.
+//
+// The selectors are type, universal, class, ID and attribute selectors with the
+// :first-child and :last-child pseudo-classes, combined by the descendant, child,
+// next-sibling and subsequent-sibling combinators.
+func InlineCSS(document string) string {
+	tokens := tokenizeHTML(document)
+
+	var (
+		rules    []*cssRule
+		retained []string
+		styles   []int
+	)
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].typ != htmlStartTag || tokens[i].data != "style" || !isInlinedStyle(&tokens[i]) {
+			continue
+		}
+
+		styles = append(styles, i)
+
+		if i+1 < len(tokens) && tokens[i+1].typ == htmlText {
+			r, rest := parseStylesheet(tokens[i+1].data, len(rules))
+			rules = append(rules, r...)
+			retained = append(retained, rest...)
+		}
+	}
+
+	if len(styles) == 0 {
+		return document
+	}
+
+	styled := make(map[int]string)
+
+	for _, n := range buildHTMLTree(tokens) {
+		if !n.rendered() {
+			continue
+		}
+
+		if style := cascade(rules, n, &tokens[n.token]); style != "" {
+			styled[n.token] = style
+		}
+	}
+
+	return renderInlined(tokens, styles, styled, retained)
+}
+
+// isInlinedStyle reports whether the rules of the <style> element apply to the screen.
+func isInlinedStyle(t *htmlToken) bool {
+	media, ok := t.attr("media")
+	if !ok {
+		return true
+	}
+
+	media = strings.ToLower(strings.TrimSpace(media))
+
+	return media == "" || media == "all" || media == "screen"
+}
+
+// renderInlined renders the tokens with the style attributes. The first of the
+// inlined <style> elements is replaced with the retained rules, the others are removed.
+func renderInlined(tokens []htmlToken, styles []int, styled map[int]string, retained []string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(tokens); i++ {
+		t := &tokens[i]
+
+		if len(styles) > 0 && i == styles[0] {
+			if len(retained) > 0 {
+				b.WriteString(t.raw + "\n" + strings.Join(retained, "\n") + "\n</style>")
+				retained = nil
+			}
+
+			// The content and the end tag of the <style> element are skipped.
+			for i+1 < len(tokens) && !(tokens[i+1].typ == htmlEndTag && tokens[i+1].data == "style") {
+				i++
+			}
+
+			if i+1 < len(tokens) {
+				i++
+			}
+
+			styles = styles[1:]
+
+			continue
+		}
+
+		if style, ok := styled[i]; ok {
+			t.setAttr("style", style)
+			b.WriteString(t.render())
+
+			continue
+		}
+
+		b.WriteString(t.raw)
+	}
+
+	return b.String()
+}
+
+// htmlNode is an element of an HTML document.
+type htmlNode struct {
+	token    int
+	name     string
+	attrs    []htmlAttr
+	parent   *htmlNode
+	children []*htmlNode
+}
+
+// attr returns the value of the attribute of the element.
+func (n *htmlNode) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.name == name {
+			return a.value, true
+		}
+	}
+
+	return "", false
+}
+
+// rendered reports whether the element is displayed, i.e. it is not in the head.
+func (n *htmlNode) rendered() bool {
+	for p := n; p != nil; p = p.parent {
+		if skippedElements[p.name] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parentElement returns the parent element or nil if the element is at the top level.
+func (n *htmlNode) parentElement() *htmlNode {
+	if n.parent == nil || n.parent.parent == nil {
+		return nil
+	}
+
+	return n.parent
+}
+
+// siblings returns the displayed children of the parent of the element. The elements
+// which are not displayed, such as the <style> elements browsers move to the head of
+// fragments, are not siblings.
+func (n *htmlNode) siblings() []*htmlNode {
+	if n.parent == nil {
+		return nil
+	}
+
+	var siblings []*htmlNode
+
+	for _, s := range n.parent.children {
+		if !skippedElements[s.name] {
+			siblings = append(siblings, s)
+		}
+	}
+
+	return siblings
+}
+
+// previousSiblings returns the sibling elements preceding the element, the closest first.
+func (n *htmlNode) previousSiblings() []*htmlNode {
+	var prev []*htmlNode
+
+	for _, s := range n.siblings() {
+		if s == n {
+			break
+		}
+
+		prev = append([]*htmlNode{s}, prev...)
+	}
+
+	return prev
+}
+
+// buildHTMLTree returns the elements of the tokens in the document order. The end
+// tags close the nearest open element of the same name, unmatched end tags are ignored.
+// The top-level elements are the children of a root node without a name, so they are
+// siblings of each other in fragments of documents too.
+func buildHTMLTree(tokens []htmlToken) []*htmlNode {
+	root := &htmlNode{}
+	stack := []*htmlNode{root}
+
+	var nodes []*htmlNode
+
+	for i := range tokens {
+		t := &tokens[i]
+
+		switch t.typ {
+		case htmlStartTag, htmlSelfClosingTag:
+			parent := stack[len(stack)-1]
+			n := &htmlNode{token: i, name: t.data, attrs: t.attrs, parent: parent}
+			parent.children = append(parent.children, n)
+			nodes = append(nodes, n)
+
+			if t.typ == htmlStartTag && !voidElements[t.data] {
+				stack = append(stack, n)
+			}
+		case htmlEndTag:
+			for j := len(stack) - 1; j > 0; j-- {
+				if stack[j].name == t.data {
+					stack = stack[:j]
+
+					break
+				}
+			}
+		}
+	}
+
+	return nodes
+}
+
+// cssDecl is a declaration of a CSS property.
+type cssDecl struct {
+	property  string
+	value     string
+	important bool
+}
+
+// cssRule is a rule of a single selector.
+type cssRule struct {
+	selector    *cssSelector
+	specificity [3]int
+	decls       []cssDecl
+	order       int
+}
+
+// parseStylesheet parses the rules of the stylesheet. It returns the rules which
+// can be inlined numbered from the order and the source of the others.
+func parseStylesheet(css string, order int) ([]*cssRule, []string) {
+	css = stripCSSComments(css)
+
+	var (
+		rules    []*cssRule
+		retained []string
+	)
+
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			return rules, retained
+		}
+
+		open := indexCSS(css, '{')
+		semi := indexCSS(css, ';')
+
+		if css[0] == '@' && semi >= 0 && (open < 0 || semi < open) {
+			// A statement at-rule, e.g. @import.
+			retained = append(retained, css[:semi+1])
+			css = css[semi+1:]
+
+			continue
+		}
+
+		if open < 0 {
+			return rules, retained
+		}
+
+		end := matchingBrace(css, open)
+		prelude := strings.TrimSpace(css[:open])
+		body := css[open+1 : end]
+
+		if end < len(css) {
+			end++
+		}
+
+		if css[0] == '@' {
+			// A block at-rule, e.g. @media or @font-face.
+			retained = append(retained, strings.TrimSpace(css[:end]))
+			css = css[end:]
+
+			continue
+		}
+
+		css = css[end:]
+		decls := parseDeclarations(body)
+
+		for _, s := range splitCSS(prelude, ',') {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+
+			sel, ok := parseSelector(s)
+			if !ok {
+				retained = append(retained, s+" { "+strings.TrimSpace(body)+" }")
+
+				continue
+			}
+
+			rules = append(rules, &cssRule{
+				selector:    sel,
+				specificity: sel.specificity(),
+				decls:       decls,
+				order:       order,
+			})
+			order++
+		}
+	}
+}
+
+// stripCSSComments removes the comments of the stylesheet.
+func stripCSSComments(css string) string {
+	var b strings.Builder
+
+	for {
+		i := strings.Index(css, "/*")
+		if i < 0 {
+			b.WriteString(css)
+
+			return b.String()
+		}
+
+		b.WriteString(css[:i])
+
+		end := strings.Index(css[i+2:], "*/")
+		if end < 0 {
+			return b.String()
+		}
+
+		css = css[i+2+end+2:]
+	}
+}
+
+// indexCSS returns the index of the first c outside of strings and parentheses.
+func indexCSS(s string, c byte) int {
+	var quote byte
+
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '(':
+			depth++
+		case s[i] == ')' && depth > 0:
+			depth--
+		case s[i] == c && depth == 0:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// matchingBrace returns the index of the brace closing the one at the open index.
+func matchingBrace(s string, open int) int {
+	depth := 0
+
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"', '\'':
+			if end := strings.IndexByte(s[i+1:], s[i]); end >= 0 {
+				i += end + 1
+			}
+		}
+	}
+
+	return len(s)
+}
+
+// splitCSS splits s by sep outside of strings and parentheses.
+func splitCSS(s string, sep byte) []string {
+	var parts []string
+
+	for {
+		i := indexCSS(s, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+// parseDeclarations parses the declarations of a rule or a style attribute.
+func parseDeclarations(s string) []cssDecl {
+	var decls []cssDecl
+
+	for _, d := range splitCSS(s, ';') {
+		i := strings.IndexByte(d, ':')
+		if i < 0 {
+			continue
+		}
+
+		decl := cssDecl{
+			property: strings.ToLower(strings.TrimSpace(d[:i])),
+			value:    strings.TrimSpace(d[i+1:]),
+		}
+
+		if j := strings.LastIndexByte(decl.value, '!'); j >= 0 &&
+			strings.EqualFold(strings.TrimSpace(decl.value[j+1:]), "important") {
+			decl.value = strings.TrimSpace(decl.value[:j])
+			decl.important = true
+		}
+
+		if decl.property != "" && decl.value != "" {
+			decls = append(decls, decl)
+		}
+	}
+
+	return decls
+}
+
+// cascadedDecl is a declaration matching an element with the precedence of its origin.
+type cascadedDecl struct {
+	decl cssDecl
+	// inline is true for the declarations of the style attribute.
+	inline      bool
+	specificity [3]int
+	order       int
+}
+
+// cascade returns the style attribute of the element with the declarations of the rules.
+// It returns an empty string if no rule matches the element.
+func cascade(rules []*cssRule, n *htmlNode, t *htmlToken) string {
+	var decls []cascadedDecl
+
+	for _, r := range rules {
+		if !r.selector.matches(n) {
+			continue
+		}
+
+		for _, d := range r.decls {
+			decls = append(decls, cascadedDecl{decl: d, specificity: r.specificity, order: r.order})
+		}
+	}
+
+	if len(decls) == 0 {
+		return ""
+	}
+
+	style, _ := t.attr("style")
+	for _, d := range parseDeclarations(style) {
+		decls = append(decls, cascadedDecl{decl: d, inline: true})
+	}
+
+	sort.SliceStable(decls, func(i, j int) bool {
+		a, b := decls[i], decls[j]
+
+		switch {
+		case a.decl.important != b.decl.important:
+			return b.decl.important
+		case a.inline != b.inline:
+			return b.inline
+		case a.specificity != b.specificity:
+			return lessSpecificity(a.specificity, b.specificity)
+		default:
+			return a.order < b.order
+		}
+	})
+
+	var (
+		properties []string
+		values     = make(map[string]cssDecl)
+	)
+
+	for _, d := range decls {
+		if _, ok := values[d.decl.property]; !ok {
+			properties = append(properties, d.decl.property)
+		}
+
+		values[d.decl.property] = d.decl
+	}
+
+	parts := make([]string, 0, len(properties))
+
+	for _, p := range properties {
+		d := values[p]
+
+		v := p + ": " + d.value
+		if d.important {
+			v += " !important"
+		}
+
+		parts = append(parts, v)
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func lessSpecificity(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+
+	return false
+}
+
+// cssSelector is a complex selector of compound selectors joined by the combinators.
+type cssSelector struct {
+	compounds []cssCompound
+	// combinators[i] joins compounds[i] and compounds[i+1].
+	combinators []byte
+}
+
+// cssCompound is a compound selector, e.g. "p.note[lang]:first-child".
+type cssCompound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []cssAttrSelector
+	pseudos []string
+}
+
+// cssAttrSelector is an attribute selector, the op is empty for the presence test.
+type cssAttrSelector struct {
+	name  string
+	op    string
+	value string
+}
+
+// supportedPseudoClasses are the pseudo-classes which can be resolved without a browser.
+var supportedPseudoClasses = map[string]bool{
+	"first-child": true,
+	"last-child":  true,
+}
+
+// parseSelector parses the complex selector, it returns false if the selector is unsupported.
+func parseSelector(s string) (*cssSelector, bool) {
+	sel := &cssSelector{}
+	pos := 0
+
+	for {
+		c, n, ok := parseCompound(s[pos:])
+		if !ok {
+			return nil, false
+		}
+
+		sel.compounds = append(sel.compounds, c)
+		pos += n
+
+		space := false
+		for pos < len(s) && isHTMLSpace(s[pos]) {
+			pos++
+			space = true
+		}
+
+		if pos == len(s) {
+			return sel, true
+		}
+
+		combinator := byte(' ')
+
+		switch s[pos] {
+		case '>', '+', '~':
+			combinator = s[pos]
+
+			pos++
+			for pos < len(s) && isHTMLSpace(s[pos]) {
+				pos++
+			}
+		default:
+			if !space {
+				return nil, false
+			}
+		}
+
+		sel.combinators = append(sel.combinators, combinator)
+	}
+}
+
+// parseCompound parses the compound selector at the start of s and returns its length.
+func parseCompound(s string) (cssCompound, int, bool) {
+	var c cssCompound
+
+	pos := 0
+
+	if pos < len(s) && s[pos] == '*' {
+		pos++
+	} else {
+		name := cssIdent(s[pos:])
+		c.tag = strings.ToLower(name)
+		pos += len(name)
+	}
+
+	for pos < len(s) {
+		switch s[pos] {
+		case '#', '.':
+			name := cssIdent(s[pos+1:])
+			if name == "" {
+				return c, 0, false
+			}
+
+			if s[pos] == '#' {
+				c.id = name
+			} else {
+				c.classes = append(c.classes, name)
+			}
+
+			pos += 1 + len(name)
+		case '[':
+			end := strings.IndexByte(s[pos:], ']')
+			if end < 0 {
+				return c, 0, false
+			}
+
+			a, ok := parseAttrSelector(s[pos+1 : pos+end])
+			if !ok {
+				return c, 0, false
+			}
+
+			c.attrs = append(c.attrs, a)
+			pos += end + 1
+		case ':':
+			name := strings.ToLower(cssIdent(s[pos+1:]))
+			if !supportedPseudoClasses[name] {
+				return c, 0, false
+			}
+
+			c.pseudos = append(c.pseudos, name)
+			pos += 1 + len(name)
+		default:
+			return c, pos, pos > 0
+		}
+	}
+
+	return c, pos, pos > 0
+}
+
+// parseAttrSelector parses the content of the brackets of an attribute selector.
+func parseAttrSelector(s string) (cssAttrSelector, bool) {
+	s = strings.TrimSpace(s)
+
+	name := cssIdent(s)
+	if name == "" {
+		return cssAttrSelector{}, false
+	}
+
+	a := cssAttrSelector{name: strings.ToLower(name)}
+
+	rest := strings.TrimSpace(s[len(name):])
+	if rest == "" {
+		return a, true
+	}
+
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(rest, op) {
+			a.op = op
+			rest = strings.TrimSpace(rest[len(op):])
+
+			break
+		}
+	}
+
+	if a.op == "" || rest == "" {
+		return a, false
+	}
+
+	if q := rest[0]; q == '"' || q == '\'' {
+		if len(rest) < 2 || rest[len(rest)-1] != q {
+			return a, false
+		}
+
+		rest = rest[1 : len(rest)-1]
+	}
+
+	a.value = rest
+
+	return a, true
+}
+
+// cssIdent returns the identifier at the start of s.
+func cssIdent(s string) string {
+	i := 0
+	for i < len(s) && (isLetter(s[i]) || '0' <= s[i] && s[i] <= '9' || s[i] == '-' || s[i] == '_' || s[i] >= 0x80) {
+		i++
+	}
+
+	return s[:i]
+}
+
+// specificity returns the number of the ID, the class-like and the type selectors.
+func (s *cssSelector) specificity() [3]int {
+	var spec [3]int
+
+	for _, c := range s.compounds {
+		if c.id != "" {
+			spec[0]++
+		}
+
+		spec[1] += len(c.classes) + len(c.attrs) + len(c.pseudos)
+
+		if c.tag != "" {
+			spec[2]++
+		}
+	}
+
+	return spec
+}
+
+// matches reports whether the element matches the selector.
+func (s *cssSelector) matches(n *htmlNode) bool {
+	return s.matchesAt(len(s.compounds)-1, n)
+}
+
+func (s *cssSelector) matchesAt(i int, n *htmlNode) bool {
+	if !s.compounds[i].matches(n) {
+		return false
+	}
+
+	if i == 0 {
+		return true
+	}
+
+	switch s.combinators[i-1] {
+	case '>':
+		p := n.parentElement()
+
+		return p != nil && s.matchesAt(i-1, p)
+	case '+':
+		prev := n.previousSiblings()
+
+		return len(prev) > 0 && s.matchesAt(i-1, prev[0])
+	case '~':
+		for _, p := range n.previousSiblings() {
+			if s.matchesAt(i-1, p) {
+				return true
+			}
+		}
+
+		return false
+	default:
+		for p := n.parentElement(); p != nil; p = p.parentElement() {
+			if s.matchesAt(i-1, p) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// matches reports whether the element matches the compound selector.
+func (c *cssCompound) matches(n *htmlNode) bool {
+	if c.tag != "" && c.tag != n.name {
+		return false
+	}
+
+	if c.id != "" {
+		if id, _ := n.attr("id"); id != c.id {
+			return false
+		}
+	}
+
+	if len(c.classes) > 0 {
+		class, _ := n.attr("class")
+		classes := strings.Fields(class)
+
+		for _, want := range c.classes {
+			if !containsString(classes, want) {
+				return false
+			}
+		}
+	}
+
+	for _, a := range c.attrs {
+		if !a.matches(n) {
+			return false
+		}
+	}
+
+	for _, p := range c.pseudos {
+		if !matchesPseudoClass(p, n) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a *cssAttrSelector) matches(n *htmlNode) bool {
+	v, ok := n.attr(a.name)
+	if !ok {
+		return false
+	}
+
+	switch a.op {
+	case "=":
+		return v == a.value
+	case "~=":
+		return containsString(strings.Fields(v), a.value)
+	case "|=":
+		return v == a.value || strings.HasPrefix(v, a.value+"-")
+	case "^=":
+		return strings.HasPrefix(v, a.value)
+	case "$=":
+		return strings.HasSuffix(v, a.value)
+	case "*=":
+		return strings.Contains(v, a.value)
+	default:
+		return true
+	}
+}
+
+func matchesPseudoClass(pseudo string, n *htmlNode) bool {
+	children := n.siblings()
+	if len(children) == 0 {
+		return false
+	}
+
+	switch pseudo {
+	case "first-child":
+		return children[0] == n
+	case "last-child":
+		return children[len(children)-1] == n
+	default:
+		return false
+	}
+}
+
+// inlinedCSS returns a copy of the text/html Part with the stylesheets of its content
+// inlined or nil if the Part has no HTML content. The content is read and rewound.
+func (p *Part) inlinedCSS() (*Part, error) {
+	if p.content == nil {
+		return nil, nil
+	}
+
+	if mediaType, _, err := p.header.ContentType(); err != nil || mediaType != mediaTypeHTML {
+		return nil, nil
+	}
+
+	document, err := p.peekContent()
+	if err != nil {
+		return nil, err
+	}
+
+	inlined := *p
+	inlined.content = strings.NewReader(InlineCSS(string(document)))
+	inlined.raw = nil
+	inlined.inlineCSS = false
+
+	return &inlined, nil
+}
@@ -0,0 +1,168 @@
+package gowl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineCSS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{
+			name:     "no style",
+			document: `<p class="x">Hello</p>`,
+			want:     `<p class="x">Hello</p>`,
+		},
+		{
+			name:     "type selector",
+			document: `<style>p { color: red; font-size: 12px }</style><p>Hello</p><div>World</div>`,
+			want:     `<p style="color: red; font-size: 12px">Hello</p><div>World</div>`,
+		},
+		{
+			name: "specificity",
+			document: `<style>#main p.note { color: green } .note { color: blue } p { color: red; margin: 0 }</style>` +
+				`<div id="main"><p class="note">A</p></div><p class="note">B</p>`,
+			want: `<div id="main"><p class="note" style="color: green; margin: 0">A</p></div>` +
+				`<p class="note" style="color: blue; margin: 0">B</p>`,
+		},
+		{
+			name:     "source order",
+			document: `<style>p { color: red } p { color: blue }</style><p>A</p>`,
+			want:     `<p style="color: blue">A</p>`,
+		},
+		{
+			name:     "style attribute",
+			document: `<style>p { color: red; margin: 0 }</style><p style="color: black; padding: 1px">A</p>`,
+			want:     `<p style="color: black; margin: 0; padding: 1px">A</p>`,
+		},
+		{
+			name:     "important",
+			document: `<style>p { color: red !important }</style><p style="color: black">A</p>`,
+			want:     `<p style="color: red !important">A</p>`,
+		},
+		{
+			name: "combinators",
+			document: `<style>ul > li { a: 1 } li + li { b: 2 } li ~ li.last { c: 3 } td:first-child { d: 4 } ` +
+				`ul li:last-child { e: 5 }</style><ul><li>1</li><li>2</li><li class="last">3</li></ul>` +
+				`<table><tr><td>x</td><td>y</td></tr></table>`,
+			want: `<ul><li style="a: 1">1</li><li style="a: 1; b: 2">2</li><li class="last" style="a: 1; b: 2; c: 3; e: 5">3</li></ul>` +
+				`<table><tr><td style="d: 4">x</td><td>y</td></tr></table>`,
+		},
+		{
+			name:     "fragment siblings",
+			document: `<style>p + p { a: 1 } p ~ div { b: 2 } p:first-child { c: 3 } div:last-child { d: 4 }</style><p>a</p><p>b</p><div>c</div>`,
+			want:     `<p style="c: 3">a</p><p style="a: 1">b</p><div style="b: 2; d: 4">c</div>`,
+		},
+		{
+			name:     "fragment descendants",
+			document: `<style>* > p { a: 1 } * p { b: 2 } div > p { c: 3 }</style><p>a</p><div><p>b</p></div>`,
+			want:     `<p>a</p><div><p style="a: 1; b: 2; c: 3">b</p></div>`,
+		},
+		{
+			name: "attribute selectors",
+			document: `<style>[href^="https"] { a: 1 } [lang|=en] { b: 2 } [data-x] { c: 3 }</style>` +
+				`<a href="https://example.com" lang="en-US">x</a><span data-x>y</span>`,
+			want: `<a href="https://example.com" lang="en-US" style="a: 1; b: 2">x</a><span data-x="" style="c: 3">y</span>`,
+		},
+		{
+			name: "retained rules",
+			document: "<html><head><style>/* layout */ @import url(\"fonts.css\");\n" +
+				"@media (max-width: 600px) { p { font-size: 16px } }\na:hover, p { color: red }</style>" +
+				`<style media="print">p { display: none }</style></head><body><p>A</p></body></html>`,
+			want: "<html><head><style>\n@import url(\"fonts.css\");\n@media (max-width: 600px) { p { font-size: 16px } }\n" +
+				"a:hover { color: red }\n</style>" +
+				`<style media="print">p { display: none }</style></head><body><p style="color: red">A</p></body></html>`,
+		},
+		{
+			name:     "escaped attributes",
+			document: `<style>a { color: red }</style><a href="?a=1&amp;b=2" title='"quoted"'>x</a>`,
+			want:     `<a href="?a=1&amp;b=2" title="&#34;quoted&#34;" style="color: red">x</a>`,
+		},
+		{
+			name:     "values with separators",
+			document: `<style>div { background: url("data:image/png;base64,AAA") no-repeat; font-family: "A;B", serif }</style><div>x</div>`,
+			want:     `<div style="background: url(&#34;data:image/png;base64,AAA&#34;) no-repeat; font-family: &#34;A;B&#34;, serif">x</div>`,
+		},
+		{
+			name: "unmodified markup",
+			document: "<!DOCTYPE html>\n<!-- <p>commented</p> --><style>p { color: red }</style>" +
+				"<DIV Class=box data-x = 'a&amp;b'>Fish &amp; chips&nbsp;&#169;</DIV><br/>" +
+				"<script>if (a < b && \"<p>\") {}</script><textarea><p>typed</p></textarea><P>A</P>",
+			want: "<!DOCTYPE html>\n<!-- <p>commented</p> -->" +
+				"<DIV Class=box data-x = 'a&amp;b'>Fish &amp; chips&nbsp;&#169;</DIV><br/>" +
+				"<script>if (a < b && \"<p>\") {}</script><textarea><p>typed</p></textarea><p style=\"color: red\">A</P>",
+		},
+		{
+			name:     "malformed markup",
+			document: "<style>p { color: red }</style>1 < 2 <3 </ b><p title=\"a>b\" <i>x</p></p><!x><p",
+			want:     "1 < 2 <3 </ b><p title=\"a&gt;b\" <i=\"\" style=\"color: red\">x</p></p><!x><p",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, gowl.InlineCSS(tt.document))
+		})
+	}
+}
+
+func TestPart_SetInlineCSS(t *testing.T) {
+	t.Parallel()
+
+	document := `<style>p { color: red }</style><p>Hello</p>`
+
+	p := gowl.NewPart(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/html"})}),
+		strings.NewReader(document),
+		nil,
+	)
+	require.False(t, p.InlineCSS())
+
+	p.SetInlineCSS(true)
+	require.True(t, p.InlineCSS())
+
+	got, err := p.Render()
+	require.NoError(t, err)
+	require.Equal(t, "Content-Type: text/html\r\n\r\n<p style=\"color: red\">Hello</p>", string(got))
+
+	// The content of the Part is kept.
+	require.Equal(t, document, partContent(t, p))
+
+	text := gowl.NewPart(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}),
+		strings.NewReader(document),
+		nil,
+	)
+	text.SetInlineCSS(true)
+
+	got, err = text.Render()
+	require.NoError(t, err)
+	require.Equal(t, "Content-Type: text/plain\r\n\r\n"+document, string(got))
+}
+
+func TestMessageBuilder_InlineCSS(t *testing.T) {
+	t.Parallel()
+
+	msg, err := gowl.NewMessageBuilder().
+		From("john.doe@example.com").
+		HTML(`<style>p { color: red }</style><p>Hello</p>`).
+		InlineCSS(true).
+		Build()
+	require.NoError(t, err)
+
+	got, err := msg.Render()
+	require.NoError(t, err)
+	require.Contains(t, string(got), "\r\n\r\n<p style=\"color: red\">Hello</p>")
+}
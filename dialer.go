@@ -0,0 +1,248 @@
+package gowl
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrTLSRequired is returned when the TLSPolicy requires an encrypted
+// connection but the server does not offer STARTTLS.
+var ErrTLSRequired = errors.New("the server does not support TLS which is required by the policy")
+
+// TLSPolicy determines whether the Dialer upgrades a plaintext connection
+// to TLS with the STARTTLS command.
+type TLSPolicy int
+
+// TLS policies of the Dialer.
+const (
+	// TLSOpportunistic upgrades the connection if the server supports STARTTLS
+	// and falls back to plaintext otherwise.
+	TLSOpportunistic TLSPolicy = iota
+	// TLSMandatory fails with ErrTLSRequired if the server does not support STARTTLS.
+	TLSMandatory
+	// NoTLS never upgrades the connection.
+	NoTLS
+)
+
+// Dialer is a configuration of connections to an SMTP server.
+type Dialer struct {
+	host      string
+	port      int
+	localName string
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	tlsPolicy TLSPolicy
+	ssl       bool
+	auths     []Auth
+}
+
+// NewDialer is a constructor of the Dialer. The Dialer uses implicit TLS
+// (SMTPS) if the port is 465, otherwise it upgrades the connection with
+// STARTTLS whenever the server supports it.
+func NewDialer(host string, port int) *Dialer {
+	return &Dialer{
+		host:      host,
+		port:      port,
+		localName: "localhost",
+		timeout:   10 * time.Second,
+		ssl:       port == 465,
+	}
+}
+
+// Reset resets the value of the Dialer but it keeps its instance (pointer).
+func (d *Dialer) Reset() {
+	*d = Dialer{}
+}
+
+// Host returns the host of the SMTP server.
+func (d *Dialer) Host() string {
+	return d.host
+}
+
+// Port returns the port of the SMTP server.
+func (d *Dialer) Port() int {
+	return d.port
+}
+
+// LocalName returns the name the Dialer introduces itself with in the EHLO command.
+func (d *Dialer) LocalName() string {
+	return d.localName
+}
+
+// Timeout returns the maximum amount of time a dial waits for a connection
+// and for the server to complete the TLS handshake and the greetings.
+func (d *Dialer) Timeout() time.Duration {
+	return d.timeout
+}
+
+// TLSConfig returns the TLS configuration of the Dialer.
+func (d *Dialer) TLSConfig() *tls.Config {
+	return d.tlsConfig
+}
+
+// TLSPolicy returns the STARTTLS policy of the Dialer.
+func (d *Dialer) TLSPolicy() TLSPolicy {
+	return d.tlsPolicy
+}
+
+// SSL reports whether the Dialer connects over an implicit TLS connection (SMTPS).
+func (d *Dialer) SSL() bool {
+	return d.ssl
+}
+
+// Auths returns the authentication mechanisms of the Dialer.
+func (d *Dialer) Auths() []Auth {
+	return d.auths
+}
+
+// SetHost replaces the host of the SMTP server.
+func (d *Dialer) SetHost(host string) {
+	d.host = host
+}
+
+// SetPort replaces the port of the SMTP server.
+func (d *Dialer) SetPort(port int) {
+	d.port = port
+}
+
+// SetLocalName replaces the name the Dialer introduces itself with in the EHLO command.
+func (d *Dialer) SetLocalName(localName string) {
+	d.localName = localName
+}
+
+// SetTimeout replaces the maximum amount of time a dial waits for a connection.
+func (d *Dialer) SetTimeout(timeout time.Duration) {
+	d.timeout = timeout
+}
+
+// SetTLSConfig replaces the TLS configuration of the Dialer. If the config
+// is nil, the default configuration with the server name set to the host is used.
+func (d *Dialer) SetTLSConfig(config *tls.Config) {
+	d.tlsConfig = config
+}
+
+// SetTLSPolicy replaces the STARTTLS policy of the Dialer.
+func (d *Dialer) SetTLSPolicy(policy TLSPolicy) {
+	d.tlsPolicy = policy
+}
+
+// SetSSL sets whether the Dialer connects over an implicit TLS connection (SMTPS).
+func (d *Dialer) SetSSL(ssl bool) {
+	d.ssl = ssl
+}
+
+// SetAuths replaces the authentication mechanisms of the Dialer. The Dialer
+// authenticates with the first mechanism the server advertises, so the order
+// of the mechanisms expresses the preference. No authentication is performed
+// if there are no mechanisms.
+func (d *Dialer) SetAuths(auths ...Auth) {
+	d.auths = auths
+}
+
+// Dial connects to the SMTP server and greets it. The connection is secured
+// according to the TLS settings of the Dialer and authenticated if the Dialer
+// has any authentication mechanisms. The returned Client is ready to send messages.
+func (d *Dialer) Dial() (*Client, error) {
+	addr := net.JoinHostPort(d.host, strconv.Itoa(d.port))
+
+	conn, err := net.DialTimeout("tcp", addr, d.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial SMTP server: %w", err)
+	}
+
+	// The timeout bounds the TLS handshake and the greetings as well,
+	// a server which stalls must not block the dial forever.
+	if d.timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(d.timeout)); err != nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("failed to set connection deadline: %w", err)
+		}
+	}
+
+	tcpConn := conn
+
+	if d.ssl {
+		conn = tls.Client(conn, tlsConfig(d.tlsConfig, d.host))
+	}
+
+	c, err := NewClient(conn, d.host)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.greet(c); err != nil {
+		_ = c.Close()
+
+		return nil, err
+	}
+
+	if err := tcpConn.SetDeadline(time.Time{}); err != nil {
+		_ = c.Close()
+
+		return nil, fmt.Errorf("failed to clear connection deadline: %w", err)
+	}
+
+	return c, nil
+}
+
+// greet says hello to the server, upgrades the connection to TLS
+// according to the policy of the Dialer and authenticates.
+func (d *Dialer) greet(c *Client) error {
+	if err := d.secure(c); err != nil {
+		return err
+	}
+
+	if len(d.auths) == 0 {
+		return nil
+	}
+
+	return c.Auth(d.auths...)
+}
+
+func (d *Dialer) secure(c *Client) error {
+	if err := c.Hello(d.localName); err != nil {
+		return err
+	}
+
+	if d.ssl || d.tlsPolicy == NoTLS {
+		return nil
+	}
+
+	ok, _, err := c.Extension("STARTTLS")
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if d.tlsPolicy == TLSMandatory {
+			return ErrTLSRequired
+		}
+
+		return nil
+	}
+
+	return c.StartTLS(d.tlsConfig)
+}
+
+// DialAndSend opens a connection to the SMTP server, sends the messages
+// from the sender to the recipients and closes the connection.
+func (d *Dialer) DialAndSend(from string, to []string, msgs ...*Message) error {
+	c, err := d.Dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for _, msg := range msgs {
+		if err := c.Send(from, to, msg); err != nil {
+			return err
+		}
+	}
+
+	return c.Quit()
+}
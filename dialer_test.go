@@ -0,0 +1,208 @@
+package gowl_test
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialer_Reset(t *testing.T) {
+	t.Parallel()
+
+	d := gowl.NewDialer("smtp.example.com", 587)
+	d.Reset()
+	require.Equal(t, &gowl.Dialer{}, d)
+}
+
+func TestDialer_Setters(t *testing.T) {
+	t.Parallel()
+
+	d := gowl.NewDialer("smtp.example.com", 587)
+	require.Equal(t, "smtp.example.com", d.Host())
+	require.Equal(t, 587, d.Port())
+	require.Equal(t, "localhost", d.LocalName())
+	require.Equal(t, 10*time.Second, d.Timeout())
+	require.Equal(t, gowl.TLSOpportunistic, d.TLSPolicy())
+	require.Nil(t, d.TLSConfig())
+	require.False(t, d.SSL())
+	require.True(t, gowl.NewDialer("smtp.example.com", 465).SSL())
+
+	d.SetHost("mail.example.com")
+	d.SetPort(25)
+	d.SetLocalName("client.example.com")
+	d.SetTimeout(time.Second)
+	d.SetTLSPolicy(gowl.TLSMandatory)
+	d.SetTLSConfig(&tls.Config{ServerName: "example.com"})
+	d.SetSSL(true)
+	require.Equal(t, "mail.example.com", d.Host())
+	require.Equal(t, 25, d.Port())
+	require.Equal(t, "client.example.com", d.LocalName())
+	require.Equal(t, time.Second, d.Timeout())
+	require.Equal(t, gowl.TLSMandatory, d.TLSPolicy())
+	require.Equal(t, &tls.Config{ServerName: "example.com"}, d.TLSConfig())
+	require.True(t, d.SSL())
+}
+
+func TestDialer_DialAndSend(t *testing.T) {
+	t.Parallel()
+
+	s := newFakeServer(t)
+
+	d := gowl.NewDialer(s.HostPort())
+	d.SetLocalName("client.example.com")
+
+	err := d.DialAndSend("john.doe@example.com", []string{"david.smith@example.com"}, testMessage(), testMessage())
+	require.NoError(t, err)
+
+	mails := s.Mails()
+	require.Len(t, mails, 2)
+	require.Equal(t, "john.doe@example.com", mails[1].From)
+	require.Equal(t, []string{"david.smith@example.com"}, mails[1].To)
+	require.Equal(t, "EHLO client.example.com", s.Commands()[0])
+	require.Equal(t, "QUIT", s.Commands()[len(s.Commands())-1])
+
+	d.SetPort(1)
+	require.Error(t, d.DialAndSend("john.doe@example.com", []string{"david.smith@example.com"}, testMessage()))
+}
+
+func TestDialer_TLS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		tls        bool
+		implicit   bool
+		policy     gowl.TLSPolicy
+		trusted    bool
+		wantSecure bool
+		wantErr    error
+		wantAnyErr bool
+	}{
+		{
+			name:       "opportunistic starttls",
+			tls:        true,
+			policy:     gowl.TLSOpportunistic,
+			trusted:    true,
+			wantSecure: true,
+		},
+		{
+			name:       "opportunistic plaintext",
+			policy:     gowl.TLSOpportunistic,
+			wantSecure: false,
+		},
+		{
+			name:    "mandatory plaintext",
+			policy:  gowl.TLSMandatory,
+			wantErr: gowl.ErrTLSRequired,
+		},
+		{
+			name:       "mandatory starttls",
+			tls:        true,
+			policy:     gowl.TLSMandatory,
+			trusted:    true,
+			wantSecure: true,
+		},
+		{
+			name:       "mandatory untrusted",
+			tls:        true,
+			policy:     gowl.TLSMandatory,
+			wantAnyErr: true,
+		},
+		{
+			name:       "no tls",
+			tls:        true,
+			policy:     gowl.NoTLS,
+			wantSecure: false,
+		},
+		{
+			name:       "implicit tls",
+			tls:        true,
+			implicit:   true,
+			trusted:    true,
+			wantSecure: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				s      *fakeServer
+				config *tls.Config
+			)
+
+			if tt.tls {
+				s, config = newFakeTLSServer(t, tt.implicit)
+			} else {
+				s = newFakeServer(t)
+			}
+
+			d := gowl.NewDialer(s.HostPort())
+			d.SetSSL(tt.implicit)
+			d.SetTLSPolicy(tt.policy)
+
+			if tt.trusted {
+				d.SetTLSConfig(config)
+			}
+
+			err := d.DialAndSend("john.doe@example.com", []string{"david.smith@example.com"}, testMessage())
+
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Empty(t, s.Mails())
+			case tt.wantAnyErr:
+				require.Error(t, err)
+				require.Empty(t, s.Mails())
+			default:
+				require.NoError(t, err)
+
+				mails := s.Mails()
+				require.Len(t, mails, 1)
+				require.Equal(t, tt.wantSecure, mails[0].Secure)
+			}
+		})
+	}
+}
+
+func TestDialer_DialTimeout(t *testing.T) {
+	t.Parallel()
+
+	// The server accepts the connections but never writes.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+
+	for _, ssl := range []bool{false, true} {
+		d := gowl.NewDialer(addr.IP.String(), addr.Port)
+		d.SetTimeout(100 * time.Millisecond)
+		d.SetSSL(ssl)
+
+		start := time.Now()
+		_, err := d.Dial()
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+		require.Less(t, time.Since(start), 5*time.Second)
+	}
+}
@@ -0,0 +1,489 @@
+package gowl
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes returned by failures to sign a Message with DKIM.
+var (
+	ErrUnsupportedKey = errors.New("the key algorithm is not supported by DKIM")
+	ErrNoFromField    = errors.New("the Message has no From field")
+)
+
+// Canonicalization is an algorithm of DKIM (RFC 6376) which prepares the header
+// fields and the body of a message for signing.
+type Canonicalization int
+
+// Canonicalization algorithms of DKIM.
+const (
+	// CanonicalizationSimple tolerates almost no modification of the message.
+	CanonicalizationSimple Canonicalization = iota
+	// CanonicalizationRelaxed tolerates the common modifications of the whitespace
+	// and the case of the field names.
+	CanonicalizationRelaxed
+)
+
+// String returns the name of the Canonicalization used in the c= tag.
+func (c Canonicalization) String() string {
+	if c == CanonicalizationRelaxed {
+		return "relaxed"
+	}
+
+	return "simple"
+}
+
+// dkimSignedHeaders are the names of the fields the DKIMSigner signs by default.
+var dkimSignedHeaders = []string{
+	"From", "Reply-To", "To", "Cc", "Subject", "Date", "Message-ID",
+	"In-Reply-To", "References", "MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// dkimSignatureLength is the length of the pieces the b= tag value is folded into.
+const dkimSignatureLength = 64
+
+// DKIMSigner signs messages with DKIM (RFC 6376) on behalf of a domain.
+type DKIMSigner struct {
+	domain     string
+	selector   string
+	key        crypto.Signer
+	headerC    Canonicalization
+	bodyC      Canonicalization
+	headers    []string
+	bodyLength bool
+}
+
+// NewDKIMSigner is a constructor of the DKIMSigner. The key is an *rsa.PrivateKey
+// or an ed25519.PrivateKey whose public key is published in DNS at the selector
+// of the domain. By default, the header and the body are canonicalized by the
+// relaxed algorithm and the common fields of the header are signed.
+func NewDKIMSigner(domain, selector string, key crypto.Signer) *DKIMSigner {
+	return &DKIMSigner{
+		domain:   domain,
+		selector: selector,
+		key:      key,
+		headerC:  CanonicalizationRelaxed,
+		bodyC:    CanonicalizationRelaxed,
+		headers:  dkimSignedHeaders,
+	}
+}
+
+// Reset resets the value of the DKIMSigner but it keeps its instance (pointer).
+func (s *DKIMSigner) Reset() {
+	*s = DKIMSigner{}
+}
+
+// Domain returns the signing domain (the d= tag).
+func (s *DKIMSigner) Domain() string {
+	return s.domain
+}
+
+// Selector returns the selector of the public key (the s= tag).
+func (s *DKIMSigner) Selector() string {
+	return s.selector
+}
+
+// Key returns the private key of the DKIMSigner.
+func (s *DKIMSigner) Key() crypto.Signer {
+	return s.key
+}
+
+// HeaderCanonicalization returns the canonicalization algorithm of the header fields.
+func (s *DKIMSigner) HeaderCanonicalization() Canonicalization {
+	return s.headerC
+}
+
+// BodyCanonicalization returns the canonicalization algorithm of the body.
+func (s *DKIMSigner) BodyCanonicalization() Canonicalization {
+	return s.bodyC
+}
+
+// Headers returns the names of the signed header fields.
+func (s *DKIMSigner) Headers() []string {
+	return s.headers
+}
+
+// BodyLength reports whether the signature states the length of the signed body (the l= tag).
+func (s *DKIMSigner) BodyLength() bool {
+	return s.bodyLength
+}
+
+// SetDomain replaces the signing domain.
+func (s *DKIMSigner) SetDomain(domain string) {
+	s.domain = domain
+}
+
+// SetSelector replaces the selector of the public key.
+func (s *DKIMSigner) SetSelector(selector string) {
+	s.selector = selector
+}
+
+// SetKey replaces the private key of the DKIMSigner.
+func (s *DKIMSigner) SetKey(key crypto.Signer) {
+	s.key = key
+}
+
+// SetHeaderCanonicalization replaces the canonicalization algorithm of the header fields.
+func (s *DKIMSigner) SetHeaderCanonicalization(c Canonicalization) {
+	s.headerC = c
+}
+
+// SetBodyCanonicalization replaces the canonicalization algorithm of the body.
+func (s *DKIMSigner) SetBodyCanonicalization(c Canonicalization) {
+	s.bodyC = c
+}
+
+// SetHeaders replaces the names of the signed header fields. Every occurrence of
+// the fields present in the message is signed, the From field is always signed.
+func (s *DKIMSigner) SetHeaders(headers []string) {
+	s.headers = headers
+}
+
+// SetBodyLength sets whether the signature states the length of the signed body.
+// It allows to append text to the body, e.g. a footer of a mailing list, without
+// breaking the signature, but also allows anyone to append content.
+func (s *DKIMSigner) SetBodyLength(bodyLength bool) {
+	s.bodyLength = bodyLength
+}
+
+// Sign adds a DKIM-Signature field at the top of the Message header. The signature
+// is computed over the rendered Message, the encodings and the boundaries are
+// resolved beforehand and the contents which do not implement io.Seeker are read
+// into memory, so the Message is rendered with the same bytes afterwards. The
+// Message must not be modified after it is signed except for prepending fields.
+func (s *DKIMSigner) Sign(m *Message) error {
+	f, err := s.signature(m, "DKIM-Signature", dkimTag{"v", "1"})
+	if err != nil {
+		return err
+	}
+
+	m.header.prependField(f)
+
+	return nil
+}
+
+// signature returns the signature field of the Message with the given name, the
+// first tag precedes the other tags. It is the DKIM-Signature or, with the instance
+// tag, the ARC-Message-Signature field.
+func (s *DKIMSigner) signature(m *Message, name string, first dkimTag) (*Field, error) {
+	algorithm, err := dkimAlgorithm(s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.rootPart.ValidateBoundaries(); err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	fields, body, err := m.wireData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	names := s.signedNames(fields)
+	if !containsFold(names, "From") {
+		return nil, ErrNoFromField
+	}
+
+	canonical := canonicalBody(s.bodyC, body)
+	bodyHash := sha256.Sum256(canonical)
+
+	tags := []dkimTag{
+		first,
+		{"a", algorithm},
+		{"c", s.headerC.String() + "/" + s.bodyC.String()},
+		{"d", s.domain},
+		{"s", s.selector},
+		{"t", strconv.FormatInt(time.Now().Unix(), 10)},
+	}
+
+	if s.bodyLength {
+		tags = append(tags, dkimTag{"l", strconv.Itoa(len(canonical))})
+	}
+
+	tags = append(tags,
+		dkimTag{"h", strings.Join(names, ":")},
+		dkimTag{"bh", base64.StdEncoding.EncodeToString(bodyHash[:])},
+		dkimTag{"b", ""},
+	)
+
+	digest := dkimHeaderHash(s.headerC, selectFields(fields, names), foldDKIMTags(name, tags))
+
+	signature, err := dkimSign(s.key, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	tags[len(tags)-1].value = base64.StdEncoding.EncodeToString(signature)
+
+	return rawField(foldDKIMTags(name, tags)), nil
+}
+
+// signedNames returns the names of the signed fields, one per occurrence in the fields.
+func (s *DKIMSigner) signedNames(fields []*Field) []string {
+	var names []string
+
+	headers := s.headers
+	if !containsFold(headers, "From") {
+		headers = append([]string{"From"}, headers...)
+	}
+
+	for _, h := range headers {
+		if containsFold(names, h) {
+			continue
+		}
+
+		for _, f := range fields {
+			if strings.EqualFold(f.name, h) {
+				names = append(names, h)
+			}
+		}
+	}
+
+	return names
+}
+
+// dkimAlgorithm returns the signing algorithm (the a= tag) of the key.
+func dkimAlgorithm(key crypto.Signer) (string, error) {
+	if key != nil {
+		switch key.Public().(type) {
+		case *rsa.PublicKey:
+			return "rsa-sha256", nil
+		case ed25519.PublicKey:
+			return "ed25519-sha256", nil
+		}
+	}
+
+	return "", ErrUnsupportedKey
+}
+
+// wireData returns the header fields and the body of the rendered Message, the
+// bytes which are put on the wire. Signatures are computed and verified over them.
+func (m *Message) wireData() ([]*Field, []byte, error) {
+	data, err := m.Render()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parseHeader(data)
+}
+
+// dkimSign signs the SHA-256 digest, Ed25519 signs the digest itself (RFC 8463).
+func dkimSign(key crypto.Signer, digest []byte) ([]byte, error) {
+	opts := crypto.Hash(0)
+	if _, ok := key.Public().(*rsa.PublicKey); ok {
+		opts = crypto.SHA256
+	}
+
+	signature, err := key.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	return signature, nil
+}
+
+// dkimHeaderHash returns the SHA-256 digest of the canonicalized signed fields
+// followed by the signature field without its b= tag value and the final CRLF.
+func dkimHeaderHash(c Canonicalization, fields []*Field, signature string) []byte {
+	h := sha256.New()
+
+	for _, f := range fields {
+		h.Write([]byte(canonicalField(c, f.raw) + "\r\n"))
+	}
+
+	h.Write([]byte(canonicalField(c, signature)))
+
+	return h.Sum(nil)
+}
+
+// selectFields returns the fields of the names. Every name selects the last
+// field of the name not selected yet, the names without a field select nothing.
+func selectFields(fields []*Field, names []string) []*Field {
+	var selected []*Field
+
+	used := make(map[*Field]bool)
+
+	for _, name := range names {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if f := fields[i]; !used[f] && strings.EqualFold(f.name, strings.TrimSpace(name)) {
+				used[f] = true
+				selected = append(selected, f)
+
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// canonicalField canonicalizes the folded field line without its final CRLF.
+func canonicalField(c Canonicalization, line string) string {
+	if c == CanonicalizationSimple {
+		return line
+	}
+
+	i := strings.IndexByte(line, ':')
+	if i < 0 {
+		return line
+	}
+
+	name := strings.ToLower(strings.TrimRight(line[:i], " \t"))
+	value := strings.NewReplacer("\r\n", "", "\n", "").Replace(line[i+1:])
+
+	return name + ":" + strings.TrimSpace(collapseWSP(value))
+}
+
+// canonicalBody canonicalizes the body, the empty lines at its end are removed.
+func canonicalBody(c Canonicalization, body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+
+	if c == CanonicalizationRelaxed {
+		for i, l := range lines {
+			lines[i] = strings.TrimRight(collapseWSP(l), " ")
+		}
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		if c == CanonicalizationRelaxed {
+			return nil
+		}
+
+		return []byte("\r\n")
+	}
+
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// collapseWSP replaces every sequence of spaces and tabs with a single space.
+func collapseWSP(s string) string {
+	var b strings.Builder
+
+	space := false
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' || s[i] == '\t' {
+			space = true
+
+			continue
+		}
+
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	if space {
+		b.WriteByte(' ')
+	}
+
+	return b.String()
+}
+
+// dkimTag is a tag of a DKIM-Signature field.
+type dkimTag struct {
+	name  string
+	value string
+}
+
+// foldDKIMTags renders the field of the tags folded into lines of at most
+// foldLineLength characters. The h= tag value is folded after its colons and the
+// b= tag value into pieces. The b= tag starts a new line, so the field with an
+// empty b= tag value equals the field with the value removed with its whitespace.
+func foldDKIMTags(name string, tags []dkimTag) string {
+	var lines []string
+
+	line := name + ":"
+
+	for i, t := range tags {
+		var pieces []string
+
+		switch t.name {
+		case "h":
+			pieces = strings.SplitAfter(t.value, ":")
+		case "b":
+			for v := t.value; v != ""; {
+				n := dkimSignatureLength
+				if n > len(v) {
+					n = len(v)
+				}
+
+				pieces = append(pieces, v[:n])
+				v = v[n:]
+			}
+		default:
+			pieces = []string{t.value}
+		}
+
+		if len(pieces) == 0 {
+			pieces = []string{""}
+		}
+
+		pieces[0] = t.name + "=" + pieces[0]
+		if i < len(tags)-1 {
+			pieces[len(pieces)-1] += ";"
+		}
+
+		for j, p := range pieces {
+			sep := ""
+			if j == 0 {
+				sep = " "
+			}
+
+			if j == 0 && t.name == "b" || len(line)+len(sep)+len(p) > foldLineLength {
+				lines = append(lines, line)
+				line = " " + p
+
+				continue
+			}
+
+			line += sep + p
+		}
+	}
+
+	return strings.Join(append(lines, line), "\r\n")
+}
+
+// rawField returns the Field of the folded line which is rendered verbatim.
+func rawField(line string) *Field {
+	i := strings.IndexByte(line, ':')
+	value := strings.TrimSpace(strings.ReplaceAll(line[i+1:], "\r\n", ""))
+
+	f := NewField(line[:i], []string{value})
+	f.raw = line
+
+	return f
+}
+
+// prependField inserts the field before the fields of the Header.
+func (h *Header) prependField(field *Field) {
+	h.fields = append([]*Field{field}, h.fields...)
+}
+
+// containsFold reports whether ss contains s ignoring the case.
+func containsFold(ss []string, s string) bool {
+	for _, v := range ss {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
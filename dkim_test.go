@@ -0,0 +1,235 @@
+package gowl_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+// dkimTestMessage returns a message with a multipart body whose text content can not be rewound.
+func dkimTestMessage(t *testing.T) *gowl.Message {
+	t.Helper()
+
+	m, err := gowl.NewMessageBuilder().
+		From("Alice <alice@example.com>").
+		To("bob@example.org").
+		Subject("Quarterly report").
+		Text("Hello Bob,\n\nthe report is attached.  \n\n\n").
+		Attach("report.csv", strings.NewReader("a,b\n1,2\n")).
+		Build()
+	require.NoError(t, err)
+
+	// A reader which does not implement io.Seeker.
+	text := m.RootPart().Parts()[0]
+	text.SetContent(bytes.NewBufferString("Hello Bob,\n\nthe report is attached.  \n\n\n"))
+
+	return m
+}
+
+// dkimTags parses the tags of the DKIM-Signature field with the folding whitespace removed.
+func dkimTags(t *testing.T, field string) map[string]string {
+	t.Helper()
+
+	value := field[strings.IndexByte(field, ':')+1:]
+	tags := make(map[string]string)
+
+	for _, tag := range strings.Split(value, ";") {
+		tag = strings.NewReplacer(" ", "", "\t", "", "\r\n", "").Replace(tag)
+		if tag == "" {
+			continue
+		}
+
+		kv := strings.SplitN(tag, "=", 2)
+		require.Len(t, kv, 2)
+		tags[kv[0]] = kv[1]
+	}
+
+	return tags
+}
+
+// verifySimpleDKIM verifies the first DKIM-Signature of the rendered message signed
+// with the simple/simple canonicalization.
+func verifySimpleDKIM(t *testing.T, data []byte, pub crypto.PublicKey) {
+	t.Helper()
+
+	i := bytes.Index(data, []byte("\r\n\r\n"))
+	require.Positive(t, i)
+
+	header, body := string(data[:i]), data[i+4:]
+
+	var fields []string
+
+	for _, line := range strings.Split(header, "\r\n") {
+		if line[0] == ' ' || line[0] == '\t' {
+			fields[len(fields)-1] += "\r\n" + line
+		} else {
+			fields = append(fields, line)
+		}
+	}
+
+	require.True(t, strings.HasPrefix(fields[0], "DKIM-Signature:"))
+
+	tags := dkimTags(t, fields[0])
+	require.Equal(t, "simple/simple", tags["c"])
+
+	canonical := append(bytes.TrimRight(body, "\r\n"), "\r\n"...)
+	bh := sha256.Sum256(canonical)
+	require.Equal(t, base64.StdEncoding.EncodeToString(bh[:]), tags["bh"])
+
+	h := sha256.New()
+	used := make(map[int]bool)
+
+	for _, name := range strings.Split(tags["h"], ":") {
+		for j := len(fields) - 1; j > 0; j-- {
+			if !used[j] && strings.EqualFold(fields[j][:strings.IndexByte(fields[j], ':')], name) {
+				used[j] = true
+
+				h.Write([]byte(fields[j] + "\r\n"))
+
+				break
+			}
+		}
+	}
+
+	h.Write([]byte(regexp.MustCompile(`b=[^;]*$`).ReplaceAllString(fields[0], "b=")))
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	require.NoError(t, err)
+
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		require.Equal(t, "rsa-sha256", tags["a"])
+		require.NoError(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, h.Sum(nil), signature))
+	case ed25519.PublicKey:
+		require.Equal(t, "ed25519-sha256", tags["a"])
+		require.True(t, ed25519.Verify(pub, h.Sum(nil), signature))
+	}
+}
+
+func TestDKIMSigner_Sign(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{"RSA-SHA256", rsaKey},
+		{"Ed25519-SHA256", edKey},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := dkimTestMessage(t)
+
+			s := gowl.NewDKIMSigner("example.com", "mail", tt.key)
+			s.SetHeaderCanonicalization(gowl.CanonicalizationSimple)
+			s.SetBodyCanonicalization(gowl.CanonicalizationSimple)
+			require.NoError(t, s.Sign(m))
+
+			data, err := m.Render()
+			require.NoError(t, err)
+
+			for _, line := range strings.Split(string(data), "\r\n") {
+				require.LessOrEqual(t, len(line), 78)
+			}
+
+			verifySimpleDKIM(t, data, tt.key.Public())
+
+			// The message is rendered with the same bytes again.
+			again, err := m.Render()
+			require.NoError(t, err)
+			require.Equal(t, data, again)
+		})
+	}
+}
+
+func TestDKIMSigner_SignTags(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	m := dkimTestMessage(t)
+	m.Header().AddField(gowl.NewField("To", []string{"carol@example.org"}))
+
+	s := gowl.NewDKIMSigner("example.com", "mail", key)
+	s.SetHeaders([]string{"Subject", "To", "X-Missing"})
+	s.SetBodyLength(true)
+	require.NoError(t, s.Sign(m))
+
+	f := m.Header().Fields()[0]
+	require.Equal(t, "DKIM-Signature", f.Name())
+
+	raw, err := f.Render()
+	require.NoError(t, err)
+
+	tags := dkimTags(t, string(raw))
+	require.Equal(t, "1", tags["v"])
+	require.Equal(t, "ed25519-sha256", tags["a"])
+	require.Equal(t, "relaxed/relaxed", tags["c"])
+	require.Equal(t, "example.com", tags["d"])
+	require.Equal(t, "mail", tags["s"])
+	require.NotEmpty(t, tags["t"])
+	// The From field is always signed and every occurrence of To is signed.
+	require.Equal(t, "From:Subject:To:To", tags["h"])
+
+	// The relaxed body canonicalization collapses the whitespace and removes the trailing empty lines.
+	data, err := m.Render()
+	require.NoError(t, err)
+
+	body := data[bytes.Index(data, []byte("\r\n\r\n"))+4:]
+	lines := strings.Split(strings.TrimRight(string(body), "\r\n"), "\r\n")
+
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(strings.Join(strings.Fields(l), " "), " ")
+		if strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t") {
+			lines[i] = " " + lines[i]
+		}
+	}
+
+	canonical := strings.Join(lines, "\r\n") + "\r\n"
+	bh := sha256.Sum256([]byte(canonical))
+	require.Equal(t, base64.StdEncoding.EncodeToString(bh[:]), tags["bh"])
+	require.Equal(t, strconv.Itoa(len(canonical)), tags["l"])
+}
+
+func TestDKIMSigner_SignError(t *testing.T) {
+	t.Parallel()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	m := dkimTestMessage(t)
+	err = gowl.NewDKIMSigner("example.com", "mail", ecKey).Sign(m)
+	require.ErrorIs(t, err, gowl.ErrUnsupportedKey)
+
+	m.Header().RemoveAll("From")
+	err = gowl.NewDKIMSigner("example.com", "mail", edKey).Sign(m)
+	require.ErrorIs(t, err, gowl.ErrNoFromField)
+	require.False(t, m.Header().Has("DKIM-Signature"))
+}
@@ -0,0 +1,523 @@
+package gowl
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes of the DKIM signatures which fail the verification.
+var (
+	ErrInvalidDKIMSignature = errors.New("the DKIM signature field is malformed")
+	ErrInvalidDKIMKey       = errors.New("the DKIM public key record is invalid")
+	ErrNoDKIMKey            = errors.New("the DKIM public key record does not exist")
+	ErrDKIMKeyLookup        = errors.New("the DKIM public key lookup failed")
+	ErrDKIMBodyHash         = errors.New("the body hash does not match the DKIM signature")
+	ErrDKIMSignature        = errors.New("the DKIM signature does not match the message")
+	ErrDKIMExpired          = errors.New("the DKIM signature has expired")
+)
+
+// minRSAKeyBits is the length of the shortest RSA key accepted by the verification (RFC 8301).
+const minRSAKeyBits = 1024
+
+// TXTResolver looks up the TXT records of a domain name, it is implemented by *net.Resolver.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DKIMStatus is a result of a DKIM signature verification (RFC 8601).
+type DKIMStatus int
+
+// Results of the DKIM signature verification.
+const (
+	// DKIMPass is the result of a valid signature.
+	DKIMPass DKIMStatus = iota
+	// DKIMFail is the result of a signature which does not match the message.
+	DKIMFail
+	// DKIMPermError is the result of a signature which can not be verified,
+	// e.g. it is malformed, it has expired or its public key does not exist.
+	DKIMPermError
+	// DKIMTempError is the result of a signature whose public key could not be
+	// retrieved, the verification may succeed later.
+	DKIMTempError
+)
+
+// String returns the name of the DKIMStatus used in the Authentication-Results field.
+func (s DKIMStatus) String() string {
+	switch s {
+	case DKIMPass:
+		return "pass"
+	case DKIMFail:
+		return "fail"
+	case DKIMPermError:
+		return "permerror"
+	default:
+		return "temperror"
+	}
+}
+
+// DKIMResult is the result of the verification of a DKIM-Signature field.
+type DKIMResult struct {
+	// Status is the result of the verification.
+	Status DKIMStatus
+	// Domain is the signing domain (the d= tag).
+	Domain string
+	// Selector is the selector of the public key (the s= tag).
+	Selector string
+	// Identifier is the agent or user identifier (the i= tag), which
+	// defaults to the signing domain preceded by '@'.
+	Identifier string
+	// Algorithm is the signing algorithm (the a= tag).
+	Algorithm string
+	// Err is the reason the signature did not pass, it is nil if it passed.
+	Err error
+}
+
+// DKIMVerifier verifies the DKIM signatures (RFC 6376) of messages.
+type DKIMVerifier struct {
+	resolver TXTResolver
+}
+
+// NewDKIMVerifier is a constructor of the DKIMVerifier. The public keys are looked
+// up by the resolver, net.DefaultResolver is used if it is nil.
+func NewDKIMVerifier(resolver TXTResolver) *DKIMVerifier {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return &DKIMVerifier{
+		resolver: resolver,
+	}
+}
+
+// Reset resets the value of the DKIMVerifier but it keeps its instance (pointer).
+func (v *DKIMVerifier) Reset() {
+	*v = DKIMVerifier{}
+}
+
+// Resolver returns the resolver of the public keys.
+func (v *DKIMVerifier) Resolver() TXTResolver {
+	return v.resolver
+}
+
+// SetResolver replaces the resolver of the public keys.
+func (v *DKIMVerifier) SetResolver(resolver TXTResolver) {
+	v.resolver = resolver
+}
+
+// Verify verifies every DKIM-Signature field of the Message and returns their
+// results in the order of the fields, it returns no results if the Message is not
+// signed. The Message is verified as it is rendered, which reads the contents not
+// implementing io.Seeker into memory.
+//
+// It returns an error only if the Message can not be rendered.
+func (v *DKIMVerifier) Verify(ctx context.Context, m *Message) ([]*DKIMResult, error) {
+	fields, body, err := m.wireData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify message: %w", err)
+	}
+
+	results := []*DKIMResult{}
+
+	for _, f := range fields {
+		if strings.EqualFold(f.name, "DKIM-Signature") {
+			results = append(results, v.verify(ctx, f.raw, fields, body))
+		}
+	}
+
+	return results, nil
+}
+
+// verify verifies the DKIM-Signature field line.
+func (v *DKIMVerifier) verify(ctx context.Context, line string, fields []*Field, body []byte) *DKIMResult {
+	r := &DKIMResult{}
+
+	sig, err := parseDKIMSignature(line)
+	if sig != nil {
+		r.Domain, r.Selector, r.Identifier, r.Algorithm = sig.domain, sig.selector, sig.identifier, sig.algorithm
+	}
+
+	if err == nil {
+		err = sig.checkDKIM()
+	}
+
+	if err != nil {
+		r.Status, r.Err = DKIMPermError, err
+
+		return r
+	}
+
+	r.Status, r.Err = verifyDKIMSignature(ctx, v.resolver, sig, fields, body)
+
+	return r
+}
+
+// dkimSignature is a parsed DKIM-Signature or ARC-Message-Signature field.
+type dkimSignature struct {
+	line       string
+	tags       map[string]string
+	algorithm  string
+	headerC    Canonicalization
+	bodyC      Canonicalization
+	domain     string
+	selector   string
+	identifier string
+	headers    []string
+	bodyHash   []byte
+	signature  []byte
+	// length is the length of the signed body, or -1 if the whole body is signed.
+	length int
+}
+
+// parseDKIMSignature parses the signature field line. It returns the signature
+// parsed so far along with the error.
+func parseDKIMSignature(line string) (*dkimSignature, error) {
+	tags, err := parseDKIMTags(line[strings.IndexByte(line, ':')+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	sig := &dkimSignature{
+		line:      line,
+		tags:      tags,
+		algorithm: tags["a"],
+		domain:    tags["d"],
+		selector:  tags["s"],
+		length:    -1,
+	}
+
+	for _, tag := range []string{"a", "b", "bh", "d", "h", "s"} {
+		if _, ok := tags[tag]; !ok {
+			return sig, fmt.Errorf("%w: missing %s= tag", ErrInvalidDKIMSignature, tag)
+		}
+	}
+
+	sig.identifier = tags["i"]
+	if sig.identifier == "" {
+		sig.identifier = "@" + sig.domain
+	}
+
+	if sig.algorithm != "rsa-sha256" && sig.algorithm != "ed25519-sha256" {
+		return sig, fmt.Errorf("%w: %s", ErrUnsupportedKey, sig.algorithm)
+	}
+
+	if sig.headerC, sig.bodyC, err = parseCanonicalization(tags["c"]); err != nil {
+		return sig, err
+	}
+
+	for _, h := range strings.Split(tags["h"], ":") {
+		sig.headers = append(sig.headers, trimFWS(h))
+	}
+
+	if !containsFold(sig.headers, "From") {
+		return sig, fmt.Errorf("%w: the From field is not signed", ErrInvalidDKIMSignature)
+	}
+
+	if sig.bodyHash, err = decodeDKIMBase64(tags["bh"]); err != nil {
+		return sig, err
+	}
+
+	if sig.signature, err = decodeDKIMBase64(tags["b"]); err != nil {
+		return sig, err
+	}
+
+	if l, ok := tags["l"]; ok {
+		if sig.length, err = strconv.Atoi(l); err != nil || sig.length < 0 {
+			return sig, fmt.Errorf("%w: invalid body length %q", ErrInvalidDKIMSignature, l)
+		}
+	}
+
+	if q, ok := tags["q"]; ok && !containsFold(strings.Split(q, ":"), "dns/txt") {
+		return sig, fmt.Errorf("%w: unsupported query method %q", ErrInvalidDKIMSignature, q)
+	}
+
+	return sig, nil
+}
+
+// checkDKIM verifies the tags specific to the DKIM-Signature field, the i= tag
+// of the ARC-Message-Signature field is its instance.
+func (sig *dkimSignature) checkDKIM() error {
+	if v := sig.tags["v"]; v != "1" {
+		return fmt.Errorf("%w: unsupported version %q", ErrInvalidDKIMSignature, v)
+	}
+
+	if i := strings.LastIndexByte(sig.identifier, '@'); i < 0 || !isSubdomain(sig.identifier[i+1:], sig.domain) {
+		return fmt.Errorf("%w: identifier %q is not in domain %s", ErrInvalidDKIMSignature, sig.identifier, sig.domain)
+	}
+
+	return nil
+}
+
+// parseDKIMTags parses the semicolon separated tag list (RFC 6376, section 3.2).
+func parseDKIMTags(value string) (map[string]string, error) {
+	tags := make(map[string]string)
+
+	for _, tag := range strings.Split(value, ";") {
+		if trimFWS(tag) == "" {
+			continue
+		}
+
+		i := strings.IndexByte(tag, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("%w: invalid tag %q", ErrInvalidDKIMSignature, trimFWS(tag))
+		}
+
+		name := trimFWS(tag[:i])
+		if _, ok := tags[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate %s= tag", ErrInvalidDKIMSignature, name)
+		}
+
+		tags[name] = trimFWS(tag[i+1:])
+	}
+
+	return tags, nil
+}
+
+// parseCanonicalization parses the c= tag, the body is canonicalized by the simple
+// algorithm unless it is stated.
+func parseCanonicalization(c string) (Canonicalization, Canonicalization, error) {
+	algorithms := map[string]Canonicalization{
+		"simple":  CanonicalizationSimple,
+		"relaxed": CanonicalizationRelaxed,
+	}
+
+	if c == "" {
+		return CanonicalizationSimple, CanonicalizationSimple, nil
+	}
+
+	header, body := c, "simple"
+	if i := strings.IndexByte(c, '/'); i >= 0 {
+		header, body = c[:i], c[i+1:]
+	}
+
+	headerC, ok := algorithms[header]
+	bodyC, ok2 := algorithms[body]
+
+	if !ok || !ok2 {
+		return 0, 0, fmt.Errorf("%w: unsupported canonicalization %q", ErrInvalidDKIMSignature, c)
+	}
+
+	return headerC, bodyC, nil
+}
+
+// verifyDKIMSignature verifies the signature of the fields and the body.
+func verifyDKIMSignature(
+	ctx context.Context, resolver TXTResolver, sig *dkimSignature, fields []*Field, body []byte,
+) (DKIMStatus, error) {
+	if x, ok := sig.tags["x"]; ok {
+		expiration, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return DKIMPermError, fmt.Errorf("%w: invalid expiration %q", ErrInvalidDKIMSignature, x)
+		}
+
+		if time.Now().Unix() > expiration {
+			// An expired signature is ignored like a malformed one (RFC 6376 section 6.1.1).
+			return DKIMPermError, fmt.Errorf("%w: at %s", ErrDKIMExpired, time.Unix(expiration, 0).UTC().Format(time.RFC3339))
+		}
+	}
+
+	key, status, err := lookupDKIMKey(ctx, resolver, sig.selector, sig.domain)
+	if err != nil {
+		return status, err
+	}
+
+	canonical := canonicalBody(sig.bodyC, body)
+	if sig.length >= 0 {
+		if sig.length > len(canonical) {
+			return DKIMFail, fmt.Errorf("%w: the body is shorter than the signed length", ErrDKIMBodyHash)
+		}
+
+		canonical = canonical[:sig.length]
+	}
+
+	bodyHash := sha256.Sum256(canonical)
+	if subtle.ConstantTimeCompare(bodyHash[:], sig.bodyHash) != 1 {
+		return DKIMFail, ErrDKIMBodyHash
+	}
+
+	digest := dkimHeaderHash(sig.headerC, selectFields(fields, sig.headers), stripDKIMSignature(sig.line))
+
+	return verifyDKIMDigest(sig.algorithm, key, digest, sig.signature)
+}
+
+// verifyDKIMDigest verifies the signature of the digest with the public key of the algorithm.
+func verifyDKIMDigest(algorithm string, key crypto.PublicKey, digest, signature []byte) (DKIMStatus, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if algorithm != "rsa-sha256" {
+			break
+		}
+
+		if key.N.BitLen() < minRSAKeyBits {
+			return DKIMPermError, fmt.Errorf("%w: the RSA key is shorter than %d bits", ErrInvalidDKIMKey, minRSAKeyBits)
+		}
+
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature); err != nil {
+			return DKIMFail, ErrDKIMSignature
+		}
+
+		return DKIMPass, nil
+	case ed25519.PublicKey:
+		if algorithm != "ed25519-sha256" {
+			break
+		}
+
+		if !ed25519.Verify(key, digest, signature) {
+			return DKIMFail, ErrDKIMSignature
+		}
+
+		return DKIMPass, nil
+	}
+
+	return DKIMPermError, fmt.Errorf("%w: the key type does not match the algorithm %s", ErrInvalidDKIMKey, algorithm)
+}
+
+// lookupDKIMKey retrieves the public key of the selector of the domain.
+func lookupDKIMKey(ctx context.Context, resolver TXTResolver, selector, domain string) (crypto.PublicKey, DKIMStatus, error) {
+	name := selector + "._domainkey." + domain
+
+	records, err := resolver.LookupTXT(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, DKIMPermError, fmt.Errorf("%w: %s", ErrNoDKIMKey, name)
+		}
+
+		return nil, DKIMTempError, fmt.Errorf("%w: %s: %v", ErrDKIMKeyLookup, name, err)
+	}
+
+	if len(records) == 0 {
+		return nil, DKIMPermError, fmt.Errorf("%w: %s", ErrNoDKIMKey, name)
+	}
+
+	for _, record := range records {
+		var key crypto.PublicKey
+		if key, err = parseDKIMKey(record); err == nil {
+			return key, DKIMPass, nil
+		}
+	}
+
+	return nil, DKIMPermError, err
+}
+
+// parseDKIMKey parses the public key record (RFC 6376, section 3.6.1).
+func parseDKIMKey(record string) (crypto.PublicKey, error) {
+	tags, err := parseDKIMTags(record)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDKIMKey, err)
+	}
+
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidDKIMKey, v)
+	}
+
+	if h, ok := tags["h"]; ok && !containsFold(strings.Split(h, ":"), "sha256") {
+		return nil, fmt.Errorf("%w: unsupported hash algorithms %q", ErrInvalidDKIMKey, h)
+	}
+
+	if s, ok := tags["s"]; ok && !containsFold(strings.Split(s, ":"), "*") && !containsFold(strings.Split(s, ":"), "email") {
+		return nil, fmt.Errorf("%w: unsupported service type %q", ErrInvalidDKIMKey, s)
+	}
+
+	p, ok := tags["p"]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing p= tag", ErrInvalidDKIMKey)
+	}
+
+	if p == "" {
+		return nil, fmt.Errorf("%w: the key is revoked", ErrInvalidDKIMKey)
+	}
+
+	data, err := decodeDKIMBase64(p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDKIMKey, err)
+	}
+
+	switch k := tags["k"]; k {
+	case "", "rsa":
+		if key, err := x509.ParsePKIXPublicKey(data); err == nil {
+			if rsaKey, ok := key.(*rsa.PublicKey); ok {
+				return rsaKey, nil
+			}
+
+			return nil, fmt.Errorf("%w: not an RSA key", ErrInvalidDKIMKey)
+		}
+
+		key, err := x509.ParsePKCS1PublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDKIMKey, err)
+		}
+
+		return key, nil
+	case "ed25519":
+		if len(data) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key length", ErrInvalidDKIMKey)
+		}
+
+		return ed25519.PublicKey(data), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidDKIMKey, k)
+	}
+}
+
+// stripDKIMSignature removes the value of the b= tag with its surrounding whitespace
+// from the signature field line.
+func stripDKIMSignature(line string) string {
+	for pos := strings.IndexByte(line, ':') + 1; pos <= len(line); {
+		end := strings.IndexByte(line[pos:], ';')
+		if end < 0 {
+			end = len(line)
+		} else {
+			end += pos
+		}
+
+		if i := strings.IndexByte(line[pos:end], '='); i >= 0 && trimFWS(line[pos:pos+i]) == "b" {
+			return line[:pos+i+1] + line[end:]
+		}
+
+		pos = end + 1
+	}
+
+	return line
+}
+
+// decodeDKIMBase64 decodes the base64 tag value, the folding whitespace is ignored.
+func decodeDKIMBase64(s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+
+		return r
+	}, s)
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid base64 value", ErrInvalidDKIMSignature)
+	}
+
+	return data, nil
+}
+
+// trimFWS removes the folding whitespace around s.
+func trimFWS(s string) string {
+	return strings.Trim(s, " \t\r\n")
+}
+
+// isSubdomain reports whether the domain equals the parent or is its subdomain.
+func isSubdomain(domain, parent string) bool {
+	domain, parent = strings.ToLower(domain), strings.ToLower(parent)
+
+	return domain == parent || strings.HasSuffix(domain, "."+parent)
+}
@@ -0,0 +1,376 @@
+package gowl_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+// txtRecords is a TXTResolver of the records in memory.
+type txtRecords map[string][]string
+
+func (r txtRecords) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return records, nil
+}
+
+// failingResolver is a TXTResolver whose lookups time out.
+type failingResolver struct{}
+
+func (failingResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+}
+
+// dkimKeyRecord returns the public key record of the key.
+func dkimKeyRecord(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+
+	switch pub := key.Public().(type) {
+	case ed25519.PublicKey:
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub)
+	default:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		require.NoError(t, err)
+
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der)
+	}
+}
+
+// signedMessage returns the rendered dkimTestMessage signed by the signer.
+func signedMessage(t *testing.T, s *gowl.DKIMSigner) []byte {
+	t.Helper()
+
+	m := dkimTestMessage(t)
+	require.NoError(t, s.Sign(m))
+
+	data, err := m.Render()
+	require.NoError(t, err)
+
+	return data
+}
+
+// verifyData parses the message and verifies its signatures.
+func verifyData(t *testing.T, resolver gowl.TXTResolver, data []byte) []*gowl.DKIMResult {
+	t.Helper()
+
+	m, err := gowl.ParseMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	results, err := gowl.NewDKIMVerifier(resolver).Verify(context.Background(), m)
+	require.NoError(t, err)
+
+	return results
+}
+
+func TestDKIMVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	records := txtRecords{
+		"rsa._domainkey.example.com": {dkimKeyRecord(t, rsaKey)},
+		"ed._domainkey.example.com":  {"unrelated record", dkimKeyRecord(t, edKey)},
+	}
+
+	canonicalizations := []gowl.Canonicalization{gowl.CanonicalizationSimple, gowl.CanonicalizationRelaxed}
+
+	for _, key := range []struct {
+		selector string
+		key      crypto.Signer
+	}{{"rsa", rsaKey}, {"ed", edKey}} {
+		for _, headerC := range canonicalizations {
+			for _, bodyC := range canonicalizations {
+				key, headerC, bodyC := key, headerC, bodyC
+
+				t.Run(key.selector+"/"+headerC.String()+"/"+bodyC.String(), func(t *testing.T) {
+					t.Parallel()
+
+					s := gowl.NewDKIMSigner("example.com", key.selector, key.key)
+					s.SetHeaderCanonicalization(headerC)
+					s.SetBodyCanonicalization(bodyC)
+
+					results := verifyData(t, records, signedMessage(t, s))
+					require.Len(t, results, 1)
+					require.NoError(t, results[0].Err)
+					require.Equal(t, gowl.DKIMPass, results[0].Status)
+					require.Equal(t, "example.com", results[0].Domain)
+					require.Equal(t, key.selector, results[0].Selector)
+					require.Equal(t, "@example.com", results[0].Identifier)
+				})
+			}
+		}
+	}
+}
+
+func TestDKIMVerifier_VerifyBuilt(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	m := dkimTestMessage(t)
+	require.NoError(t, gowl.NewDKIMSigner("example.com", "ed", key).Sign(m))
+	require.NoError(t, gowl.NewDKIMSigner("example.org", "ed", key).Sign(m))
+
+	records := txtRecords{"ed._domainkey.example.com": {dkimKeyRecord(t, key)}}
+
+	results, err := gowl.NewDKIMVerifier(records).Verify(context.Background(), m)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "example.org", results[0].Domain)
+	require.Equal(t, gowl.DKIMPermError, results[0].Status)
+	require.ErrorIs(t, results[0].Err, gowl.ErrNoDKIMKey)
+	require.Equal(t, gowl.DKIMPass, results[1].Status)
+}
+
+func TestDKIMVerifier_VerifyParsed(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	records := txtRecords{"ed._domainkey.example.com": {dkimKeyRecord(t, key)}}
+
+	data, err := dkimTestMessage(t).Render()
+	require.NoError(t, err)
+
+	m, err := gowl.ParseMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.NoError(t, gowl.NewDKIMSigner("example.com", "ed", key).Sign(m))
+
+	v := gowl.NewDKIMVerifier(records)
+
+	results, err := v.Verify(context.Background(), m)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, gowl.DKIMPass, results[0].Status)
+
+	// The content of a nested part is changed after the message is signed.
+	m.RootPart().Parts()[0].SetContent(strings.NewReader("Hello Eve"))
+
+	results, err = v.Verify(context.Background(), m)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, gowl.DKIMFail, results[0].Status)
+	require.ErrorIs(t, results[0].Err, gowl.ErrDKIMBodyHash)
+}
+
+func TestDKIMVerifier_VerifyModified(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	records := txtRecords{"ed._domainkey.example.com": {dkimKeyRecord(t, key)}}
+
+	tests := []struct {
+		name     string
+		simple   bool
+		length   bool
+		modify   func(string) string
+		wantStat gowl.DKIMStatus
+		wantErr  error
+	}{
+		{
+			name:     "changed subject",
+			modify:   func(s string) string { return strings.Replace(s, "Quarterly report", "Annual report", 1) },
+			wantStat: gowl.DKIMFail,
+			wantErr:  gowl.ErrDKIMSignature,
+		},
+		{
+			name:     "changed body",
+			modify:   func(s string) string { return strings.Replace(s, "Hello Bob", "Hello Eve", 1) },
+			wantStat: gowl.DKIMFail,
+			wantErr:  gowl.ErrDKIMBodyHash,
+		},
+		{
+			name:     "added signed field",
+			modify:   func(s string) string { return strings.Replace(s, "\r\n\r\n", "\r\nSubject: Urgent\r\n\r\n", 1) },
+			wantStat: gowl.DKIMFail,
+			wantErr:  gowl.ErrDKIMSignature,
+		},
+		{
+			name:     "added unsigned field",
+			modify:   func(s string) string { return strings.Replace(s, "\r\n\r\n", "\r\nX-Spam: no\r\n\r\n", 1) },
+			wantStat: gowl.DKIMPass,
+		},
+		{
+			name: "relaxed whitespace",
+			modify: func(s string) string {
+				return strings.Replace(s, "Subject: Quarterly report", "subject:\tQuarterly  \r\n report ", 1)
+			},
+			wantStat: gowl.DKIMPass,
+		},
+		{
+			name:   "simple whitespace",
+			simple: true,
+			modify: func(s string) string {
+				return strings.Replace(s, "Subject: Quarterly report", "Subject: Quarterly  report", 1)
+			},
+			wantStat: gowl.DKIMFail,
+			wantErr:  gowl.ErrDKIMSignature,
+		},
+		{
+			name:     "appended empty lines",
+			simple:   true,
+			modify:   func(s string) string { return s + "\r\n\r\n" },
+			wantStat: gowl.DKIMPass,
+		},
+		{
+			name:     "appended footer",
+			length:   true,
+			modify:   func(s string) string { return s + "\r\n-- \r\nList footer\r\n" },
+			wantStat: gowl.DKIMPass,
+		},
+		{
+			name:     "appended footer without length",
+			modify:   func(s string) string { return s + "\r\n-- \r\nList footer\r\n" },
+			wantStat: gowl.DKIMFail,
+			wantErr:  gowl.ErrDKIMBodyHash,
+		},
+		{
+			name:     "unknown algorithm",
+			modify:   func(s string) string { return strings.Replace(s, "a=ed25519-sha256", "a=rsa-sha1", 1) },
+			wantStat: gowl.DKIMPermError,
+			wantErr:  gowl.ErrUnsupportedKey,
+		},
+		{
+			name:     "missing key",
+			modify:   func(s string) string { return strings.Replace(s, "s=ed;", "s=missing;", 1) },
+			wantStat: gowl.DKIMPermError,
+			wantErr:  gowl.ErrNoDKIMKey,
+		},
+		{
+			name:     "foreign identifier",
+			modify:   func(s string) string { return strings.Replace(s, "s=ed;", "s=ed; i=@example.net;", 1) },
+			wantStat: gowl.DKIMPermError,
+			wantErr:  gowl.ErrInvalidDKIMSignature,
+		},
+		{
+			name:     "expired",
+			modify:   func(s string) string { return strings.Replace(s, "s=ed;", "s=ed; x=1;", 1) },
+			wantStat: gowl.DKIMPermError,
+			wantErr:  gowl.ErrDKIMExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := gowl.NewDKIMSigner("example.com", "ed", key)
+			s.SetBodyLength(tt.length)
+
+			if tt.simple {
+				s.SetHeaderCanonicalization(gowl.CanonicalizationSimple)
+				s.SetBodyCanonicalization(gowl.CanonicalizationSimple)
+			}
+
+			data := tt.modify(string(signedMessage(t, s)))
+
+			results := verifyData(t, records, []byte(data))
+			require.Len(t, results, 1)
+			require.Equal(t, tt.wantStat, results[0].Status, "%v", results[0].Err)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, results[0].Err, tt.wantErr)
+			} else {
+				require.NoError(t, results[0].Err)
+			}
+		})
+	}
+}
+
+func TestDKIMVerifier_VerifyKey(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	data := signedMessage(t, gowl.NewDKIMSigner("example.com", "ed", key))
+
+	tests := []struct {
+		name     string
+		resolver gowl.TXTResolver
+		wantStat gowl.DKIMStatus
+		wantErr  error
+	}{
+		{
+			name:     "revoked",
+			resolver: txtRecords{"ed._domainkey.example.com": {"v=DKIM1; k=ed25519; p="}},
+			wantStat: gowl.DKIMPermError,
+			wantErr:  gowl.ErrInvalidDKIMKey,
+		},
+		{
+			name:     "key type mismatch",
+			resolver: txtRecords{"ed._domainkey.example.com": {dkimKeyRecord(t, rsaKey)}},
+			wantStat: gowl.DKIMPermError,
+			wantErr:  gowl.ErrInvalidDKIMKey,
+		},
+		{
+			name:     "other service",
+			resolver: txtRecords{"ed._domainkey.example.com": {dkimKeyRecord(t, key) + "; s=web"}},
+			wantStat: gowl.DKIMPermError,
+			wantErr:  gowl.ErrInvalidDKIMKey,
+		},
+		{
+			name:     "lookup timeout",
+			resolver: failingResolver{},
+			wantStat: gowl.DKIMTempError,
+			wantErr:  gowl.ErrDKIMKeyLookup,
+		},
+		{
+			name:     "wrong key",
+			resolver: txtRecords{"ed._domainkey.example.com": {"k=ed25519; p=" + base64.StdEncoding.EncodeToString(make([]byte, 32))}},
+			wantStat: gowl.DKIMFail,
+			wantErr:  gowl.ErrDKIMSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			results := verifyData(t, tt.resolver, data)
+			require.Len(t, results, 1)
+			require.Equal(t, tt.wantStat, results[0].Status)
+			require.True(t, errors.Is(results[0].Err, tt.wantErr), "%v", results[0].Err)
+		})
+	}
+}
+
+func TestDKIMVerifier_VerifyUnsigned(t *testing.T) {
+	t.Parallel()
+
+	m := dkimTestMessage(t)
+
+	results, err := gowl.NewDKIMVerifier(txtRecords{}).Verify(context.Background(), m)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
@@ -0,0 +1,187 @@
+package gowl
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// wordDecoder decodes RFC 2047 encoded words of the header values.
+var wordDecoder = &mime.WordDecoder{}
+
+// unstructuredFields are the header fields which values are free text (RFC 5322 section 3.6.5).
+var unstructuredFields = map[string]bool{
+	"subject":             true,
+	"comments":            true,
+	"keywords":            true,
+	"thread-topic":        true,
+	"content-description": true,
+}
+
+// addressFields are the header fields which values are lists of addresses (RFC 5322 section 3.6.2 and 3.6.3).
+var addressFields = map[string]bool{
+	"from":                        true,
+	"sender":                      true,
+	"reply-to":                    true,
+	"to":                          true,
+	"cc":                          true,
+	"bcc":                         true,
+	"resent-from":                 true,
+	"resent-sender":               true,
+	"resent-to":                   true,
+	"resent-cc":                   true,
+	"resent-bcc":                  true,
+	"disposition-notification-to": true,
+}
+
+// phraseSpecials are characters which must not occur in a Q-encoded word inside of a phrase.
+const phraseSpecials = `"(),.:;<>@[\]`
+
+// encodeWord returns the shorter of the B and Q encoded-word forms of s.
+// If s is printable ASCII, it is returned unchanged.
+func encodeWord(s string, phrase bool) string {
+	b := mime.BEncoding.Encode("UTF-8", s)
+	if b == s {
+		return s
+	}
+
+	q := mime.QEncoding.Encode("UTF-8", s)
+	if phrase && strings.ContainsAny(q, phraseSpecials) {
+		return b
+	}
+
+	if len(b) < len(q) {
+		return b
+	}
+
+	return q
+}
+
+// encodeValue encodes the non-ASCII text of the value of the field with the
+// given name into RFC 2047 encoded words. Unstructured fields are encoded as
+// a whole, only display names are encoded in address fields, and the values
+// of other structured fields are kept unchanged.
+func encodeValue(name, value string) string {
+	switch n := strings.ToLower(name); {
+	case unstructuredFields[n] || strings.HasPrefix(n, "x-"):
+		return encodeWord(value, false)
+	case addressFields[n]:
+		return encodeAddressList(value)
+	default:
+		return value
+	}
+}
+
+// encodeAddressList encodes the non-ASCII display names of the addresses in the list.
+// Lists which can not be parsed are split on commas and encoded address by address.
+func encodeAddressList(list string) string {
+	if mime.QEncoding.Encode("UTF-8", list) == list {
+		return list
+	}
+
+	if addresses, err := ParseAddressList(list); err == nil {
+		return formatAddressList(addresses, true)
+	}
+
+	changed := false
+
+	addrs := splitAddressList(list)
+	for i, a := range addrs {
+		if e := encodeAddress(a); e != a {
+			addrs[i] = e
+			changed = true
+		}
+	}
+
+	if !changed {
+		return list
+	}
+
+	for i, a := range addrs {
+		addrs[i] = strings.TrimSpace(a)
+	}
+
+	return strings.Join(addrs, ", ")
+}
+
+// encodeAddress encodes the non-ASCII display name of the address "Display Name <addr-spec>".
+func encodeAddress(addr string) string {
+	i := strings.LastIndexByte(addr, '<')
+	if i <= 0 {
+		return addr
+	}
+
+	name := strings.TrimSpace(addr[:i])
+	if strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) && len(name) > 1 {
+		name = strings.ReplaceAll(name[1:len(name)-1], `\"`, `"`)
+	}
+
+	encoded := encodeWord(name, true)
+	if encoded == name {
+		return addr
+	}
+
+	return encoded + " " + strings.TrimSpace(addr[i:])
+}
+
+// splitAddressList splits the list of addresses on commas which are
+// outside of quoted strings, comments and angle brackets.
+func splitAddressList(list string) []string {
+	var (
+		addrs   []string
+		quoted  bool
+		escaped bool
+		depth   int
+		start   int
+	)
+
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '<':
+			depth++
+		case (c == ')' || c == '>') && depth > 0:
+			depth--
+		case c == ',' && depth == 0:
+			addrs = append(addrs, list[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(addrs, list[start:])
+}
+
+// DecodeHeader decodes all RFC 2047 encoded words in the header value.
+// Words in UTF-8, ISO-8859-1 and US-ASCII charsets are supported.
+func DecodeHeader(value string) (string, error) {
+	s, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode header value: %w", err)
+	}
+
+	return s, nil
+}
+
+// DecodedValues returns the values of the Field with all RFC 2047 encoded words decoded.
+func (f *Field) DecodedValues() ([]string, error) {
+	values := make([]string, len(f.values))
+
+	for i, v := range f.values {
+		s, err := DecodeHeader(v)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = s
+	}
+
+	return values, nil
+}
@@ -0,0 +1,99 @@
+package gowl_test
+
+import (
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestField_RenderEncodedWords(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		field  *gowl.Field
+		want   string
+		decode string
+	}{
+		{
+			name:   "ascii subject",
+			field:  gowl.NewField("Subject", []string{"Hello, world!"}),
+			want:   "Subject: Hello, world!",
+			decode: "Hello, world!",
+		},
+		{
+			name:   "latin subject",
+			field:  gowl.NewField("Subject", []string{"Your order for Zoë is ready"}),
+			want:   "Subject: =?UTF-8?q?Your_order_for_Zo=C3=AB_is_ready?=",
+			decode: "Your order for Zoë is ready",
+		},
+		{
+			name:   "emoji subject",
+			field:  gowl.NewField("Subject", []string{"🎉🎉🎉"}),
+			want:   "Subject: =?UTF-8?b?8J+OifCfjonwn46J?=",
+			decode: "🎉🎉🎉",
+		},
+		{
+			name:   "unquoted display name",
+			field:  gowl.NewField("From", []string{"Zoë Müller <zoe@example.com>"}),
+			want:   "From: =?UTF-8?b?Wm/DqyBNw7xsbGVy?= <zoe@example.com>",
+			decode: "Zoë Müller <zoe@example.com>",
+		},
+		{
+			name:   "quoted display name with specials",
+			field:  gowl.NewField("To", []string{`"Müller, Zoë" <zoe@example.com>, Jo <jo@x.org>`}),
+			want:   "To: =?UTF-8?b?TcO8bGxlciwgWm/Dqw==?= <zoe@example.com>, Jo <jo@x.org>",
+			decode: "Müller, Zoë <zoe@example.com>, Jo <jo@x.org>",
+		},
+		{
+			name:   "ascii address list",
+			field:  gowl.NewField("Cc", []string{`"Doe, John" <john@example.com>,jane@example.com`}),
+			want:   `Cc: "Doe, John" <john@example.com>,jane@example.com`,
+			decode: `"Doe, John" <john@example.com>,jane@example.com`,
+		},
+		{
+			name:   "structured field",
+			field:  gowl.NewField("Content-Type", []string{"text/plain", `name="Grüße.txt"`}),
+			want:   `Content-Type: text/plain; name="Grüße.txt"`,
+			decode: "text/plain",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.field.Render()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+
+			decoded, err := gowl.DecodeHeader(string(got[len(tt.field.Name())+2:]))
+			require.NoError(t, err)
+			require.Contains(t, decoded, tt.decode)
+		})
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	t.Parallel()
+
+	got, err := gowl.DecodeHeader("=?ISO-8859-1?q?Gr=FC=DFe?= =?UTF-8?b?4pyT?=")
+	require.NoError(t, err)
+	require.Equal(t, "Grüße✓", got)
+
+	_, err = gowl.DecodeHeader("=?x-unknown?q?abc?=")
+	require.Error(t, err)
+
+	f := gowl.NewField("Subject", []string{"=?UTF-8?q?Zo=C3=AB?=", "plain"})
+	values, err := f.DecodedValues()
+	require.NoError(t, err)
+	require.Equal(t, []string{"Zoë", "plain"}, values)
+
+	f = gowl.NewField("Subject", []string{"=?x-unknown?q?abc?="})
+	values, err = f.DecodedValues()
+	require.Error(t, err)
+	require.Nil(t, values)
+}
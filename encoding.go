@@ -0,0 +1,251 @@
+package gowl
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// Error codes returned by failures to encode a content of a Part.
+var (
+	ErrUnknownEncoding = errors.New("the Content-Transfer-Encoding is not supported")
+	ErrNot7bit         = errors.New("the content contains 8-bit data but its encoding is 7bit")
+)
+
+// Content transfer encodings supported by the Part rendering.
+const (
+	Encoding7bit            = "7bit"
+	Encoding8bit            = "8bit"
+	EncodingBinary          = "binary"
+	EncodingBase64          = "base64"
+	EncodingQuotedPrintable = "quoted-printable"
+	// EncodingAuto is not a valid MIME encoding. When a Part is rendered, it is
+	// replaced with the cheapest encoding which can represent the content safely.
+	EncodingAuto = "auto"
+)
+
+// maxLineLength is the maximum length of a line of the encoded content excluding CRLF.
+const maxLineLength = 76
+
+// transferEncoding returns the lower-cased Content-Transfer-Encoding of the Header.
+func (h *Header) transferEncoding() string {
+	if f := h.Get("Content-Transfer-Encoding"); f != nil && len(f.values) > 0 {
+		return strings.ToLower(strings.TrimSpace(f.values[0]))
+	}
+
+	return ""
+}
+
+// setTransferEncoding replaces the value of the Content-Transfer-Encoding field.
+func (h *Header) setTransferEncoding(encoding string) {
+	if f := h.Get("Content-Transfer-Encoding"); f != nil {
+		f.SetValues([]string{encoding})
+
+		return
+	}
+
+	h.AddField(NewField("Content-Transfer-Encoding", []string{encoding}))
+}
+
+// resolveEncoding replaces the auto Content-Transfer-Encoding of the Part with the
+// cheapest encoding of its content. The content is scanned and rewound, contents
+// which do not implement io.Seeker are read into memory.
+func (p *Part) resolveEncoding() error {
+	if p.content == nil || p.header.transferEncoding() != EncodingAuto {
+		return nil
+	}
+
+	if err := p.bufferContent(); err != nil {
+		return err
+	}
+
+	s := p.content.(io.ReadSeeker)
+
+	offset, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to seek part content: %w", err)
+	}
+
+	stats := contentStats{}
+	if _, err := io.Copy(&stats, s); err != nil {
+		return fmt.Errorf("failed to read part content: %w", err)
+	}
+
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek part content: %w", err)
+	}
+
+	p.header.setTransferEncoding(stats.encoding())
+
+	return nil
+}
+
+// encoder returns a writer which encodes the content written to it with the
+// given transfer encoding and writes it to w. The writer must be closed.
+func encoder(w io.Writer, encoding string) (io.WriteCloser, error) {
+	switch encoding {
+	case "", Encoding8bit:
+		return nopCloser{newCRLFWriter(w)}, nil
+	case Encoding7bit:
+		return nopCloser{&sevenBitWriter{w: newCRLFWriter(w)}}, nil
+	case EncodingBinary:
+		return nopCloser{w}, nil
+	case EncodingBase64:
+		return base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: w}), nil
+	case EncodingQuotedPrintable:
+		return quotedprintable.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEncoding, encoding)
+	}
+}
+
+// decoder returns a reader which decodes the content read from r encoded
+// with the given transfer encoding.
+func decoder(r io.Reader, encoding string) (io.Reader, error) {
+	switch encoding {
+	case "", Encoding7bit, Encoding8bit, EncodingBinary:
+		return r, nil
+	case EncodingBase64:
+		return base64.NewDecoder(base64.StdEncoding, r), nil
+	case EncodingQuotedPrintable:
+		return quotedprintable.NewReader(r), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEncoding, encoding)
+	}
+}
+
+// nopCloser adds a no-op Close method to a writer.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// sevenBitWriter fails if 8-bit data is written.
+type sevenBitWriter struct {
+	w io.Writer
+}
+
+func (sw *sevenBitWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b >= 0x80 || b == 0 {
+			return 0, ErrNot7bit
+		}
+	}
+
+	return sw.w.Write(p)
+}
+
+// lineWrapper breaks the written data into lines of maxLineLength characters.
+type lineWrapper struct {
+	w   io.Writer
+	col int
+}
+
+func (lw *lineWrapper) Write(p []byte) (int, error) {
+	buf := make([]byte, 0, len(p)+len(p)/maxLineLength*2+2)
+
+	for _, b := range p {
+		if lw.col == maxLineLength {
+			buf = append(buf, crlf...)
+			lw.col = 0
+		}
+
+		buf = append(buf, b)
+		lw.col++
+	}
+
+	if _, err := lw.w.Write(buf); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// contentStats collects statistics of a content to choose its transfer encoding.
+type contentStats struct {
+	size     int
+	escaped  int
+	control  int
+	nonASCII int
+	binary   bool
+	lineLen  int
+	longLine bool
+	cr       bool
+}
+
+func (cs *contentStats) Write(p []byte) (int, error) {
+	for _, b := range p {
+		cs.size++
+
+		switch {
+		case b == '\n':
+			cs.lineLen = 0
+		case cs.cr:
+			// A bare CR can not be represented in any text encoding.
+			cs.binary = true
+		case b == 0:
+			cs.binary = true
+		case b >= 0x80:
+			cs.nonASCII++
+			cs.escaped++
+		case b < ' ' && b != '\t' && b != '\r':
+			cs.control++
+			cs.escaped++
+		case b == '=':
+			cs.escaped++
+		}
+
+		if b != '\n' && b != '\r' {
+			cs.lineLen++
+			if cs.lineLen > 998 {
+				cs.longLine = true
+			}
+		}
+
+		cs.cr = b == '\r'
+	}
+
+	return len(p), nil
+}
+
+// encoding returns the cheapest transfer encoding which represents the content safely.
+func (cs *contentStats) encoding() string {
+	if cs.cr {
+		cs.binary = true
+	}
+
+	switch {
+	case cs.binary:
+		return EncodingBase64
+	case cs.nonASCII == 0 && cs.control == 0 && !cs.longLine:
+		return Encoding7bit
+	}
+
+	// Every escaped byte takes 3 characters in quoted-printable,
+	// base64 takes 4 characters for every 3 bytes.
+	if cs.size+2*cs.escaped <= cs.size*4/3 {
+		return EncodingQuotedPrintable
+	}
+
+	return EncodingBase64
+}
+
+// writeEncoded copies the content to w encoded with the given transfer encoding.
+func writeEncoded(w io.Writer, content io.Reader, encoding string) error {
+	enc, err := encoder(w, encoding)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(enc, content); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
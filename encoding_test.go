@@ -0,0 +1,202 @@
+package gowl_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPart_RenderEncoding(t *testing.T) {
+	t.Parallel()
+
+	binary := []byte{0x00, 0xff, '\n', 0x10, '\r', 0x80, 'a'}
+
+	tests := []struct {
+		name     string
+		encoding string
+		content  io.Reader
+		want     string
+		wantErr  error
+	}{
+		{
+			name:    "no encoding",
+			content: strings.NewReader("Hello,\nworld!"),
+			want:    "Hello,\r\nworld!",
+		},
+		{
+			name:     "7bit",
+			encoding: "7bit",
+			content:  strings.NewReader("Hello,\nworld!"),
+			want:     "Hello,\r\nworld!",
+		},
+		{
+			name:     "7bit with 8-bit data",
+			encoding: "7bit",
+			content:  strings.NewReader("Zoë"),
+			wantErr:  gowl.ErrNot7bit,
+		},
+		{
+			name:     "8bit",
+			encoding: "8bit",
+			content:  strings.NewReader("Zoë Müller\n"),
+			want:     "Zoë Müller\r\n",
+		},
+		{
+			name:     "binary",
+			encoding: "binary",
+			content:  bytes.NewReader(binary),
+			want:     string(binary),
+		},
+		{
+			name:     "base64",
+			encoding: "base64",
+			content:  bytes.NewReader(binary),
+			want:     base64.StdEncoding.EncodeToString(binary),
+		},
+		{
+			name:     "base64 upper case",
+			encoding: "Base64",
+			content:  strings.NewReader("This is a test file.\n"),
+			want:     "VGhpcyBpcyBhIHRlc3QgZmlsZS4K",
+		},
+		{
+			name:     "base64 line wrapping",
+			encoding: "base64",
+			content:  strings.NewReader(strings.Repeat("a", 120)),
+			want: strings.Repeat("YWFh", 19) + "\r\n" +
+				strings.Repeat("YWFh", 19) + "\r\n" +
+				strings.Repeat("YWFh", 2),
+		},
+		{
+			name:     "quoted-printable",
+			encoding: "quoted-printable",
+			content:  strings.NewReader("Zoë Müller = test \nNext line."),
+			want:     "Zo=C3=AB M=C3=BCller =3D test=20\r\nNext line.",
+		},
+		{
+			name:     "quoted-printable soft line break",
+			encoding: "quoted-printable",
+			content:  strings.NewReader(strings.Repeat("a", 80)),
+			want:     strings.Repeat("a", 75) + "=\r\n" + strings.Repeat("a", 5),
+		},
+		{
+			name:     "unknown",
+			encoding: "x-uuencode",
+			content:  strings.NewReader("Hello"),
+			wantErr:  gowl.ErrUnknownEncoding,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fields := []*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}
+			if tt.encoding != "" {
+				fields = append(fields, gowl.NewField("Content-Transfer-Encoding", []string{tt.encoding}))
+			}
+
+			p := gowl.NewPart(gowl.NewHeader(fields), tt.content, nil)
+			head, err := p.Header().Render()
+			require.NoError(t, err)
+
+			got, err := p.Render()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, string(head)+"\r\n\r\n"+tt.want, string(got))
+		})
+	}
+}
+
+func TestPart_RenderEncodingAuto(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content io.Reader
+		want    string
+	}{
+		{
+			name:    "ascii",
+			content: strings.NewReader("Hello,\nworld! 1 + 1 = 2"),
+			want:    gowl.Encoding7bit,
+		},
+		{
+			name:    "mostly ascii",
+			content: strings.NewReader("Dear Zoë Müller,\nthank you for your order."),
+			want:    gowl.EncodingQuotedPrintable,
+		},
+		{
+			name:    "long line",
+			content: strings.NewReader(strings.Repeat("a", 1000)),
+			want:    gowl.EncodingQuotedPrintable,
+		},
+		{
+			name:    "non-latin",
+			content: strings.NewReader("こんにちは世界、これはテストです。"),
+			want:    gowl.EncodingBase64,
+		},
+		{
+			name:    "binary",
+			content: bytes.NewReader([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00}),
+			want:    gowl.EncodingBase64,
+		},
+		{
+			name:    "bare cr",
+			content: strings.NewReader("Hello\rworld"),
+			want:    gowl.EncodingBase64,
+		},
+		{
+			name:    "non-seekable",
+			content: io.LimitReader(strings.NewReader("Viele Grüße aus Berlin"), 100),
+			want:    gowl.EncodingQuotedPrintable,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := gowl.NewPart(
+				gowl.NewHeader([]*gowl.Field{
+					gowl.NewField("Content-Type", []string{"text/plain"}),
+					gowl.NewField("Content-Transfer-Encoding", []string{gowl.EncodingAuto}),
+				}),
+				tt.content,
+				nil,
+			)
+
+			got, err := p.Render()
+			require.NoError(t, err)
+			require.Equal(t, []string{tt.want}, p.Header().Fields()[1].Values())
+			require.Contains(t, string(got), "Content-Transfer-Encoding: "+tt.want+"\r\n\r\n")
+
+			again, err := p.Render()
+			require.NoError(t, err)
+			require.Equal(t, string(got), string(again))
+		})
+	}
+
+	p := gowl.NewPart(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Transfer-Encoding", []string{gowl.EncodingAuto})}),
+		errReader{},
+		nil,
+	)
+	_, err := p.Render()
+	require.ErrorIs(t, err, ErrInvalidReader)
+}
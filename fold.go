@@ -0,0 +1,119 @@
+package gowl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrLineTooLong is returned when a header field can not be folded into lines
+// of at most maxHeaderLineLength characters.
+var ErrLineTooLong = errors.New("the header line exceeds the limit of 998 characters")
+
+const (
+	// foldLineLength is the recommended maximum length of a header line excluding CRLF.
+	foldLineLength = 78
+	// maxHeaderLineLength is the maximum length of a header line excluding CRLF.
+	maxHeaderLineLength = 998
+)
+
+// foldField folds the unfolded header field line into lines of at most
+// foldLineLength characters if possible. Lines are broken before a whitespace,
+// whitespaces following a semicolon and then a comma are preferred, and whitespaces
+// in quoted strings are skipped. Folds already present in the line are kept.
+// It returns ErrLineTooLong if a line still exceeds maxHeaderLineLength.
+func foldField(line string) (string, error) {
+	segments := strings.Split(line, "\n")
+	lines := make([]string, 0, len(segments))
+
+	for i, s := range segments {
+		s = strings.TrimSuffix(s, "\r")
+
+		// The preserved folds must be followed by a whitespace to be unfolded correctly.
+		start := 0
+		if i > 0 && !strings.HasPrefix(s, " ") && !strings.HasPrefix(s, "\t") {
+			s = " " + s
+		} else if i == 0 {
+			start = strings.Index(s, ":") + 2
+		}
+
+		lines = append(lines, foldLine(s, start)...)
+	}
+
+	for _, l := range lines {
+		if len(l) > maxHeaderLineLength {
+			return "", fmt.Errorf("%w: %.40s...", ErrLineTooLong, l)
+		}
+	}
+
+	return strings.Join(lines, "\r\n"), nil
+}
+
+// foldLine breaks the line into lines of at most foldLineLength characters.
+// The first break is not placed before the position start.
+func foldLine(s string, start int) []string {
+	var lines []string
+
+	for len(s) > foldLineLength {
+		i := foldPoint(s, start)
+		if i < 0 {
+			break
+		}
+
+		lines = append(lines, s[:i])
+		s = s[i:]
+		start = 1
+	}
+
+	return append(lines, s)
+}
+
+// foldPoint returns the position of the whitespace where the line should be
+// broken, or -1 if it can not be broken.
+func foldPoint(s string, start int) int {
+	var (
+		quoted, escaped         bool
+		last, semi, comma, next = -1, -1, -1, -1
+	)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted || i < start || (c != ' ' && c != '\t'):
+		case strings.TrimLeft(s[i:], " \t") == "" || strings.TrimLeft(s[:i], " \t") == "":
+			// Neither of the lines may consist of whitespaces only.
+		case i > foldLineLength:
+			next = i
+		default:
+			last = i
+			switch s[i-1] {
+			case ';':
+				semi = i
+			case ',':
+				comma = i
+			}
+		}
+
+		if next >= 0 {
+			break
+		}
+	}
+
+	switch {
+	case semi > foldLineLength/2:
+		return semi
+	case comma > foldLineLength/2:
+		return comma
+	case last > 0:
+		return last
+	default:
+		return next
+	}
+}
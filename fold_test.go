@@ -0,0 +1,103 @@
+package gowl_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestField_RenderFolding(t *testing.T) {
+	t.Parallel()
+
+	var (
+		recipients []string
+		references []string
+	)
+
+	for i := 0; i < 10; i++ {
+		recipients = append(recipients, fmt.Sprintf("User %d <user.%d@example.com>", i, i))
+		references = append(references, fmt.Sprintf("<%d.1615644030@mail.example.com>", i))
+	}
+
+	tests := []struct {
+		name    string
+		field   *gowl.Field
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "short",
+			field: gowl.NewField("Subject", []string{"Hello, world!"}),
+			want:  "Subject: Hello, world!",
+		},
+		{
+			name:  "address list",
+			field: gowl.NewField("To", []string{strings.Join(recipients, ", ")}),
+			want: "To: User 0 <user.0@example.com>, User 1 <user.1@example.com>,\r\n" +
+				" User 2 <user.2@example.com>, User 3 <user.3@example.com>,\r\n" +
+				" User 4 <user.4@example.com>, User 5 <user.5@example.com>,\r\n" +
+				" User 6 <user.6@example.com>, User 7 <user.7@example.com>,\r\n" +
+				" User 8 <user.8@example.com>, User 9 <user.9@example.com>",
+		},
+		{
+			name:  "references",
+			field: gowl.NewField("References", []string{strings.Join(references[:4], " ")}),
+			want: "References: <0.1615644030@mail.example.com> <1.1615644030@mail.example.com>\r\n" +
+				" <2.1615644030@mail.example.com> <3.1615644030@mail.example.com>",
+		},
+		{
+			name:  "parameters",
+			field: gowl.NewField("Content-Type", []string{"text/plain", `charset="UTF-8"`, `name="` + strings.Repeat("a", 50) + `.txt"`}),
+			want:  "Content-Type: text/plain; charset=\"UTF-8\";\r\n name=\"" + strings.Repeat("a", 50) + ".txt\"",
+		},
+		{
+			name:  "quoted string",
+			field: gowl.NewField("Content-Disposition", []string{"attachment", `filename="` + strings.Repeat("long name ", 8) + `"`}),
+			want:  "Content-Disposition: attachment;\r\n filename=\"" + strings.Repeat("long name ", 8) + "\"",
+		},
+		{
+			name:  "preserved folds",
+			field: gowl.NewField("DKIM-Signature", []string{"v=1;\r\n a=rsa-sha256;\n\tb=abc"}),
+			want:  "DKIM-Signature: v=1;\r\n a=rsa-sha256;\r\n\tb=abc",
+		},
+		{
+			name:  "unbreakable",
+			field: gowl.NewField("Message-ID", []string{"<" + strings.Repeat("a", 100) + "@example.com>"}),
+			want:  "Message-ID: <" + strings.Repeat("a", 100) + "@example.com>",
+		},
+		{
+			name:    "too long",
+			field:   gowl.NewField("Message-ID", []string{"<" + strings.Repeat("a", 1000) + "@example.com>"}),
+			wantErr: gowl.ErrLineTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.field.Render()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+
+	h := gowl.NewHeader([]*gowl.Field{
+		gowl.NewField("Subject", []string{"Test"}),
+		gowl.NewField("Message-ID", []string{"<" + strings.Repeat("a", 1000) + "@example.com>"}),
+	})
+	_, err := h.Render()
+	require.ErrorIs(t, err, gowl.ErrLineTooLong)
+}
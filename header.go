@@ -3,6 +3,7 @@ package gowl
 import (
 	"bytes"
 	"errors"
+	"io"
 	"strings"
 )
 
@@ -39,10 +40,11 @@ func (h *Header) AddField(field *Field) {
 	h.fields = append(h.fields, field)
 }
 
-// RemoveField removes a field with a given name in the fields of the Header.
+// RemoveField removes the first field with a given case-insensitive name in the fields
+// of the Header. Use RemoveAll to remove all fields with the name.
 func (h *Header) RemoveField(name string) {
 	for i, f := range h.fields {
-		if f.name == name {
+		if strings.EqualFold(f.name, name) {
 			h.fields = append(h.fields[:i], h.fields[i+1:]...)
 
 			break
@@ -50,26 +52,171 @@ func (h *Header) RemoveField(name string) {
 	}
 }
 
+// Get returns the first field with a given case-insensitive name, or nil if the Header has none.
+func (h *Header) Get(name string) *Field {
+	for _, f := range h.fields {
+		if strings.EqualFold(f.name, name) {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// GetAll returns all fields with a given case-insensitive name in their order in the Header.
+func (h *Header) GetAll(name string) []*Field {
+	var fields []*Field
+
+	for _, f := range h.fields {
+		if strings.EqualFold(f.name, name) {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// Has reports whether the Header has a field with a given case-insensitive name.
+func (h *Header) Has(name string) bool {
+	return h.Get(name) != nil
+}
+
+// Set replaces all fields with a given case-insensitive name by a single field
+// with the values. The field keeps the position of the first replaced field,
+// it is appended to the end of the Header if there is none.
+func (h *Header) Set(name string, values []string) {
+	fields := h.fields[:0]
+	set := false
+
+	for _, f := range h.fields {
+		if !strings.EqualFold(f.name, name) {
+			fields = append(fields, f)
+
+			continue
+		}
+
+		if !set {
+			f.SetName(name)
+			f.SetValues(values)
+			fields = append(fields, f)
+			set = true
+		}
+	}
+
+	h.fields = fields
+
+	if !set {
+		h.AddField(NewField(name, values))
+	}
+}
+
+// RemoveAll removes all fields with a given case-insensitive name from the Header.
+func (h *Header) RemoveAll(name string) {
+	fields := h.fields[:0]
+
+	for _, f := range h.fields {
+		if !strings.EqualFold(f.name, name) {
+			fields = append(fields, f)
+		}
+	}
+
+	h.fields = fields
+}
+
+// Canonicalize renames all fields of the Header to their canonical names,
+// see CanonicalFieldName. Renamed fields lose the folding they were parsed with.
+func (h *Header) Canonicalize() {
+	for _, f := range h.fields {
+		if c := CanonicalFieldName(f.name); c != f.name {
+			f.SetName(c)
+		}
+	}
+}
+
+// canonicalNames are the canonical names which do not follow the capitalization of words.
+var canonicalNames = map[string]string{
+	"arc-authentication-results": "ARC-Authentication-Results",
+	"arc-message-signature":      "ARC-Message-Signature",
+	"arc-seal":                   "ARC-Seal",
+	"content-id":                 "Content-ID",
+	"content-md5":                "Content-MD5",
+	"dkim-signature":             "DKIM-Signature",
+	"list-id":                    "List-ID",
+	"message-id":                 "Message-ID",
+	"mime-version":               "MIME-Version",
+	"resent-message-id":          "Resent-Message-ID",
+}
+
+// CanonicalFieldName returns the canonical form of the field name, the first letter
+// of every hyphen-separated word is upper-cased and the rest is lower-cased, e.g.
+// "content-type" becomes "Content-Type". Well-known names such as "Message-ID" and
+// "MIME-Version" keep their conventional form. Invalid names are returned unchanged.
+func CanonicalFieldName(name string) string {
+	lower := strings.ToLower(name)
+	if c, ok := canonicalNames[lower]; ok {
+		return c
+	}
+
+	b := []byte(lower)
+	upper := true
+
+	for i, c := range b {
+		if c <= ' ' || c >= 0x7f || c == ':' {
+			return name
+		}
+
+		if upper && 'a' <= c && c <= 'z' {
+			b[i] = c - 'a' + 'A'
+		}
+
+		upper = c == '-'
+	}
+
+	return string(b)
+}
+
 // Render renders the Header fields and returns them in bytes.
-// It renders each field on its own line.
+// It renders each field on its own line separated by CRLF.
 func (h *Header) Render() ([]byte, error) {
-	fs := make([][]byte, len(h.fields))
+	buf := bytes.Buffer{}
+
+	if _, err := h.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes the rendered Header fields to w. It writes each field on its
+// own line separated by CRLF. It returns the number of bytes written.
+func (h *Header) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
 
-	var err error
 	for i, f := range h.fields {
-		if fs[i], err = f.Render(); err != nil {
-			return nil, err
+		field, err := f.Render()
+		if err != nil {
+			return cw.n, err
+		}
+
+		if i > 0 {
+			if _, err := cw.Write(crlf); err != nil {
+				return cw.n, err
+			}
+		}
+
+		if _, err := cw.Write(field); err != nil {
+			return cw.n, err
 		}
 	}
 
-	return bytes.Join(fs, []byte{'\n'}), nil
+	return cw.n, nil
 }
 
 // Boundary queries the fields of the Header and tries to find a boundary of the Content-Type.
 // If there's no boundary parameter inside its Content-Type ErrNoBoundary is returned.
 func (h *Header) Boundary() ([]byte, error) {
 	for _, f := range h.fields {
-		if f.name == "Content-Type" {
+		if strings.EqualFold(f.name, "Content-Type") {
 			if v := f.Param("boundary"); v != nil {
 				return v, nil
 			}
@@ -83,6 +230,9 @@ func (h *Header) Boundary() ([]byte, error) {
 type Field struct {
 	name   string
 	values []string
+	// raw is the folded line of a parsed field, it is rendered instead of
+	// the values until the field is modified.
+	raw string
 }
 
 // NewField is a constructor of a Field.
@@ -111,25 +261,27 @@ func (f *Field) Values() []string {
 // SetName rewrites the name of the value of the Field.
 func (f *Field) SetName(name string) {
 	f.name = name
+	f.raw = ""
 }
 
 // SetValues rewrites the values value of the Field.
 func (f *Field) SetValues(values []string) {
 	f.values = values
+	f.raw = ""
 }
 
 // AddValue appends given value to the end of the values of the Field.
 func (f *Field) AddValue(value string) {
 	f.values = append(f.values, value)
+	f.raw = ""
 }
 
-// Param returns the value of the parameter param of the Field.
+// Param returns the decoded value of the parameter param of the Field.
+// The name of the parameter is case-insensitive. It returns nil if the Field
+// has no such parameter.
 func (f *Field) Param(param string) []byte {
-	param += "="
-	for _, v := range f.values {
-		if strings.Contains(v, param) {
-			return []byte(strings.Trim(strings.TrimPrefix(v, param), "\""))
-		}
+	if v, ok := f.Params()[strings.ToLower(param)]; ok {
+		return []byte(v)
 	}
 
 	return nil
@@ -137,10 +289,29 @@ func (f *Field) Param(param string) []byte {
 
 // Render renders the content of the field into bytes. It returns formatted
 // SMTP Field of the Header. The values of the Field are separated by semicolons.
+// Non-ASCII text of unstructured fields (e.g. Subject) and display names of
+// address fields (e.g. From) is encoded into RFC 2047 encoded words.
+// Long fields are folded into lines of at most 78 characters where possible,
+// ErrLineTooLong is returned if a line can not be folded within 998 characters.
+// Fields read by ParseMessage are rendered as they were read until they are modified.
 func (f *Field) Render() ([]byte, error) {
 	if len(f.values) == 0 {
 		return nil, ErrNoValues
 	}
 
-	return []byte(f.name + ": " + strings.Join(f.values, "; ")), nil
+	if f.raw != "" {
+		return []byte(f.raw), nil
+	}
+
+	values := make([]string, len(f.values))
+	for i, v := range f.values {
+		values[i] = encodeValue(f.name, v)
+	}
+
+	line, err := foldField(f.name + ": " + strings.Join(values, "; "))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(line), nil
 }
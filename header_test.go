@@ -1,6 +1,7 @@
 package gowl_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/chutommy/gowl"
@@ -67,6 +68,82 @@ func TestHeader_RemoveField(t *testing.T) {
 	require.Equal(t, fields, got)
 }
 
+func received() []*gowl.Field {
+	return []*gowl.Field{
+		gowl.NewField("Received", []string{"from a.example.com by b.example.com", "Sat, 13 Mar 2021 07:00:30 -0800"}),
+		gowl.NewField("content-type", []string{"text/plain"}),
+		gowl.NewField("RECEIVED", []string{"from c.example.com by a.example.com", "Sat, 13 Mar 2021 07:00:29 -0800"}),
+		gowl.NewField("Subject", []string{"Test"}),
+	}
+}
+
+func TestHeader_Get(t *testing.T) {
+	t.Parallel()
+
+	fields := received()
+	h := gowl.NewHeader(fields)
+
+	require.Equal(t, fields[1], h.Get("Content-Type"))
+	require.Equal(t, fields[0], h.Get("received"))
+	require.Nil(t, h.Get("Date"))
+	require.Equal(t, []*gowl.Field{fields[0], fields[2]}, h.GetAll("Received"))
+	require.Nil(t, h.GetAll("Date"))
+	require.True(t, h.Has("SUBJECT"))
+	require.False(t, h.Has("Date"))
+}
+
+func TestHeader_Set(t *testing.T) {
+	t.Parallel()
+
+	h := gowl.NewHeader(received())
+	h.Set("Received", []string{"from d.example.com"})
+	h.Set("Date", []string{"Sat, 13 Mar 2021 07:00:31 -0800"})
+
+	got, err := h.Render()
+	require.NoError(t, err)
+	require.Equal(t, string(crlf(`Received: from d.example.com
+content-type: text/plain
+Subject: Test
+Date: Sat, 13 Mar 2021 07:00:31 -0800`)), string(got))
+}
+
+func TestHeader_RemoveAll(t *testing.T) {
+	t.Parallel()
+
+	fields := received()
+	h := gowl.NewHeader(fields)
+	h.RemoveField("CONTENT-TYPE")
+	require.Len(t, h.Fields(), 3)
+
+	h.RemoveAll("received")
+	require.Equal(t, []*gowl.Field{fields[3]}, h.Fields())
+
+	h.RemoveAll("Subject")
+	require.Empty(t, h.Fields())
+}
+
+func TestHeader_Canonicalize(t *testing.T) {
+	t.Parallel()
+
+	h := gowl.NewHeader([]*gowl.Field{
+		gowl.NewField("content-type", []string{"text/plain"}),
+		gowl.NewField("MESSAGE-ID", []string{"<1@example.com>"}),
+		gowl.NewField("x-mailer", []string{"gowl"}),
+		gowl.NewField("mime-version", []string{"1.0"}),
+		gowl.NewField("dkim-signature", []string{"v=1"}),
+	})
+	h.Canonicalize()
+
+	var names []string
+	for _, f := range h.Fields() {
+		names = append(names, f.Name())
+	}
+
+	require.Equal(t, []string{"Content-Type", "Message-ID", "X-Mailer", "MIME-Version", "DKIM-Signature"}, names)
+	require.Equal(t, "Invalid Name", gowl.CanonicalFieldName("Invalid Name"))
+	require.Equal(t, "X-Ms-Exchange-Test", gowl.CanonicalFieldName("x-MS-exchange-test"))
+}
+
 func TestHeader_Render(t *testing.T) {
 	t.Parallel()
 
@@ -91,11 +168,12 @@ func TestHeader_Render(t *testing.T) {
 					gowl.NewField("Content-Type", []string{"multipart/alternative", "boundary=\"37a48tbyab7wot468rls798t3y5fcz4t\""}),
 				},
 			},
-			want: []byte(`From: John Doe <john.doe@example.com>
+			want: crlf(`From: John Doe <john.doe@example.com>
 To: Thomas Smith <thomas.smith@example.com>
 Date: Wed, 8 Mar 2021 12:45:10 +0100
 MIME-Version: 1.0
-Content-Type: multipart/alternative; boundary="37a48tbyab7wot468rls798t3y5fcz4t"`,
+Content-Type: multipart/alternative;
+ boundary="37a48tbyab7wot468rls798t3y5fcz4t"`,
 			),
 			wantErr: nil,
 		},
@@ -368,7 +446,7 @@ func TestField_Render(t *testing.T) {
 				Name:   "Received",
 				Values: []string{"by 1010:abc:abcd:0:0:0:0:0 with SMTP id 123456789abcdef", "Sat, 13 Mar 2021 07:00:30 -0800 (PST)"},
 			},
-			want:    []byte("Received: by 1010:abc:abcd:0:0:0:0:0 with SMTP id 123456789abcdef; Sat, 13 Mar 2021 07:00:30 -0800 (PST)"),
+			want:    []byte("Received: by 1010:abc:abcd:0:0:0:0:0 with SMTP id 123456789abcdef;\r\n Sat, 13 Mar 2021 07:00:30 -0800 (PST)"),
 			wantErr: nil,
 		},
 		{
@@ -393,3 +471,25 @@ func TestField_Render(t *testing.T) {
 		})
 	}
 }
+
+func TestHeader_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	h := gowl.NewHeader([]*gowl.Field{
+		gowl.NewField("From", []string{"John Doe <john.doe@example.com>"}),
+		gowl.NewField("To", []string{"Thomas Smith <thomas.smith@example.com>"}),
+	})
+
+	buf := bytes.Buffer{}
+	n, err := h.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), n)
+	require.Equal(t, "From: John Doe <john.doe@example.com>\r\nTo: Thomas Smith <thomas.smith@example.com>", buf.String())
+
+	_, err = h.WriteTo(&limitWriter{n: 40})
+	require.ErrorIs(t, err, ErrInvalidWriter)
+
+	h.AddField(gowl.NewField("Subject", nil))
+	_, err = h.WriteTo(&buf)
+	require.ErrorIs(t, err, gowl.ErrNoValues)
+}
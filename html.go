@@ -0,0 +1,159 @@
+package gowl
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlTokenType is a type of an htmlToken.
+type htmlTokenType int
+
+// Types of the htmlTokens.
+const (
+	htmlText htmlTokenType = iota
+	htmlStartTag
+	htmlEndTag
+	htmlSelfClosingTag
+	htmlComment
+	htmlDoctype
+)
+
+// htmlAttr is an attribute of a tag, the name is lower-cased and the value unescaped.
+type htmlAttr struct {
+	name  string
+	value string
+}
+
+// htmlToken is a token of an HTML document. The data is the lower-cased name of
+// a tag or the raw text, the raw is the source of the token.
+type htmlToken struct {
+	typ   htmlTokenType
+	data  string
+	attrs []htmlAttr
+	raw   string
+}
+
+// attr returns the value of the attribute of the tag.
+func (t *htmlToken) attr(name string) (string, bool) {
+	for _, a := range t.attrs {
+		if a.name == name {
+			return a.value, true
+		}
+	}
+
+	return "", false
+}
+
+// setAttr replaces the value of the attribute of the tag or adds it.
+func (t *htmlToken) setAttr(name, value string) {
+	for i, a := range t.attrs {
+		if a.name == name {
+			t.attrs[i].value = value
+
+			return
+		}
+	}
+
+	t.attrs = append(t.attrs, htmlAttr{name: name, value: value})
+}
+
+// render returns the source of the tag with its current attributes.
+func (t *htmlToken) render() string {
+	if t.typ != htmlStartTag && t.typ != htmlSelfClosingTag {
+		return t.raw
+	}
+
+	var b strings.Builder
+
+	b.WriteString("<" + t.data)
+
+	for _, a := range t.attrs {
+		b.WriteString(" " + a.name + `="` + html.EscapeString(a.value) + `"`)
+	}
+
+	if t.typ == htmlSelfClosingTag {
+		b.WriteString(" /")
+	}
+
+	b.WriteString(">")
+
+	return b.String()
+}
+
+// tokenizeHTML splits the HTML document into tokens with the tokenizer of the HTML5
+// parsing algorithm, it does not build a tree or fix the nesting of the elements.
+// The content of a raw text element such as <script> or <style> is a single text
+// token and malformed markup is tokenized the way browsers do. The raw sources of
+// the tokens concatenate to the document.
+func tokenizeHTML(s string) []htmlToken {
+	var (
+		tokens []htmlToken
+		n      int
+	)
+
+	z := html.NewTokenizer(strings.NewReader(s))
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// The reader of the document never fails, the error is io.EOF. A tag cut
+			// off by the end of the document is ignored like a comment but it is kept
+			// in the tokens.
+			if n < len(s) {
+				tokens = append(tokens, htmlToken{typ: htmlComment, raw: s[n:]})
+			}
+
+			return tokens
+		}
+
+		raw := string(z.Raw())
+		n += len(raw)
+
+		switch tt {
+		case html.TextToken:
+			tokens = append(tokens, htmlToken{typ: htmlText, data: raw, raw: raw})
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			tokens = append(tokens, newTagToken(z, tt, raw))
+		case html.CommentToken:
+			tokens = append(tokens, htmlToken{typ: htmlComment, data: string(z.Text()), raw: raw})
+		case html.DoctypeToken:
+			tokens = append(tokens, htmlToken{typ: htmlDoctype, data: string(z.Text()), raw: raw})
+		}
+	}
+}
+
+// newTagToken returns the tag the tokenizer is positioned at. Only the first of the
+// duplicate attributes is kept, the attributes of the end tags are dropped.
+func newTagToken(z *html.Tokenizer, tt html.TokenType, raw string) htmlToken {
+	name, more := z.TagName()
+	t := htmlToken{typ: htmlStartTag, data: string(name), raw: raw}
+
+	switch tt {
+	case html.EndTagToken:
+		t.typ = htmlEndTag
+
+		return t
+	case html.SelfClosingTagToken:
+		t.typ = htmlSelfClosingTag
+	}
+
+	for more {
+		var key, value []byte
+
+		key, value, more = z.TagAttr()
+		if _, ok := t.attr(string(key)); !ok {
+			t.attrs = append(t.attrs, htmlAttr{name: string(key), value: string(value)})
+		}
+	}
+
+	return t
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
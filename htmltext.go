@@ -0,0 +1,467 @@
+package gowl
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// textLineLength is the length the lines of the text derived from HTML are wrapped at.
+const textLineLength = 76
+
+// skippedElements are the elements whose content is not part of the text.
+var skippedElements = map[string]bool{
+	"head":     true,
+	"script":   true,
+	"style":    true,
+	"title":    true,
+	"template": true,
+}
+
+// blockElements are the elements which are separated from the surrounding text by an empty line.
+var blockElements = map[string]bool{
+	"p":          true,
+	"div":        true,
+	"section":    true,
+	"article":    true,
+	"header":     true,
+	"footer":     true,
+	"main":       true,
+	"nav":        true,
+	"aside":      true,
+	"address":    true,
+	"figure":     true,
+	"form":       true,
+	"fieldset":   true,
+	"dl":         true,
+	"dd":         true,
+	"dt":         true,
+	"center":     true,
+	"figcaption": true,
+}
+
+// HTMLToText derives a plain text alternative of the HTML document. The text
+// is wrapped at 76 characters, the headings are prefixed by '#' characters, the
+// list items by "*" or their numbers, the quotations by "> " and the cells of the
+// table rows are separated by " | ". The links are referenced by numbers in
+// brackets and listed at the end of the text.
+func HTMLToText(document string) string {
+	c := textConverter{}
+
+	for _, t := range tokenizeHTML(document) {
+		c.token(&t)
+	}
+
+	return c.finish()
+}
+
+// textList is a list being converted to text.
+type textList struct {
+	ordered bool
+	n       int
+}
+
+// textConverter converts the HTML tokens to text.
+type textConverter struct {
+	out   strings.Builder
+	line  strings.Builder
+	space bool
+	// sep is the number of line breaks requested before the next text.
+	sep int
+
+	skip   []string
+	pre    int
+	quotes int
+	// lastQuotes is the quotation level of the last written line.
+	lastQuotes int
+	lists      []textList
+	// marker is the prefix of the first line of a list item or a heading,
+	// hang is the indentation of the other lines of the list item.
+	marker string
+	hang   string
+	cells  int
+
+	link     string
+	linkText strings.Builder
+	links    []string
+}
+
+func (c *textConverter) token(t *htmlToken) {
+	if len(c.skip) > 0 {
+		if t.typ == htmlEndTag && t.data == c.skip[len(c.skip)-1] {
+			c.skip = c.skip[:len(c.skip)-1]
+		} else if t.typ == htmlStartTag && skippedElements[t.data] {
+			c.skip = append(c.skip, t.data)
+		}
+
+		return
+	}
+
+	switch t.typ {
+	case htmlText:
+		c.text(html.UnescapeString(t.data))
+	case htmlStartTag, htmlSelfClosingTag:
+		if skippedElements[t.data] && t.typ == htmlStartTag {
+			c.skip = append(c.skip, t.data)
+
+			return
+		}
+
+		c.start(t)
+	case htmlEndTag:
+		c.end(t.data)
+	}
+}
+
+func (c *textConverter) start(t *htmlToken) {
+	switch name := t.data; {
+	case name == "br":
+		if c.line.Len() > 0 {
+			c.flush()
+		} else {
+			// Consecutive line breaks separate paragraphs.
+			c.breakLines(2)
+		}
+	case name == "hr":
+		c.block(2)
+		c.writeLine(strings.Repeat("-", 20))
+		c.block(2)
+	case len(name) == 2 && name[0] == 'h' && '1' <= name[1] && name[1] <= '6':
+		c.block(2)
+		c.marker = strings.Repeat("#", int(name[1]-'0')) + " "
+	case name == "ul" || name == "ol":
+		if len(c.lists) == 0 {
+			c.block(2)
+		} else {
+			c.block(1)
+		}
+
+		c.lists = append(c.lists, textList{ordered: name == "ol"})
+	case name == "li":
+		c.block(1)
+
+		if len(c.lists) == 0 {
+			c.marker = "* "
+		} else {
+			l := &c.lists[len(c.lists)-1]
+			l.n++
+
+			c.marker = "* "
+			if l.ordered {
+				c.marker = strconv.Itoa(l.n) + ". "
+			}
+		}
+
+		c.hang = strings.Repeat(" ", utf8.RuneCountInString(c.marker))
+	case name == "blockquote":
+		c.block(2)
+		c.quotes++
+	case name == "pre":
+		c.block(2)
+		c.pre++
+	case name == "table":
+		c.block(2)
+	case name == "tr":
+		c.block(1)
+		c.cells = 0
+	case name == "td" || name == "th":
+		if c.cells > 0 {
+			c.space = false
+			c.line.WriteString(" | ")
+		}
+
+		c.cells++
+	case name == "a":
+		c.link = ""
+		c.linkText.Reset()
+
+		if href, ok := t.attr("href"); ok && isFootnoteLink(href) {
+			c.link = strings.TrimSpace(href)
+		}
+	case name == "img":
+		if alt, ok := t.attr("alt"); ok {
+			c.text(alt)
+		}
+	case blockElements[name]:
+		c.block(2)
+	}
+}
+
+func (c *textConverter) end(name string) {
+	switch {
+	case len(name) == 2 && name[0] == 'h' && '1' <= name[1] && name[1] <= '6':
+		c.block(2)
+		c.marker = ""
+	case name == "ul" || name == "ol":
+		if len(c.lists) > 0 {
+			c.lists = c.lists[:len(c.lists)-1]
+		}
+
+		if len(c.lists) == 0 {
+			c.block(2)
+		} else {
+			c.block(1)
+		}
+	case name == "li":
+		c.block(1)
+		c.marker, c.hang = "", ""
+	case name == "blockquote":
+		c.block(2)
+
+		if c.quotes > 0 {
+			c.quotes--
+		}
+	case name == "pre":
+		c.block(2)
+
+		if c.pre > 0 {
+			c.pre--
+		}
+	case name == "table":
+		c.block(2)
+	case name == "tr":
+		c.block(1)
+	case name == "a":
+		c.endLink()
+	case blockElements[name]:
+		c.block(2)
+	}
+}
+
+// isFootnoteLink reports whether the link is listed in the footnotes.
+func isFootnoteLink(href string) bool {
+	href = strings.ToLower(strings.TrimSpace(href))
+
+	return href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(href, "javascript:") &&
+		!strings.HasPrefix(href, "cid:")
+}
+
+// endLink adds the footnote reference of the link unless its text is the URL itself.
+func (c *textConverter) endLink() {
+	if c.link == "" {
+		return
+	}
+
+	text := strings.TrimSpace(c.linkText.String())
+	link := c.link
+	c.link = ""
+
+	if text == link || "mailto:"+text == link || text == "" {
+		if text == "" {
+			c.text(link)
+		}
+
+		return
+	}
+
+	n := 0
+
+	for i, l := range c.links {
+		if l == link {
+			n = i + 1
+
+			break
+		}
+	}
+
+	if n == 0 {
+		c.links = append(c.links, link)
+		n = len(c.links)
+	}
+
+	c.line.WriteString(fmt.Sprintf(" [%d]", n))
+}
+
+// text appends the text, the whitespace is collapsed outside of preformatted blocks.
+func (c *textConverter) text(s string) {
+	if c.link != "" {
+		c.linkText.WriteString(s)
+	}
+
+	if c.pre > 0 {
+		lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+		for i, l := range lines {
+			if i > 0 {
+				c.writeLine(c.line.String())
+				c.line.Reset()
+			}
+
+			c.line.WriteString(l)
+		}
+
+		return
+	}
+
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			c.space = c.line.Len() > 0
+
+			continue
+		}
+
+		if c.space {
+			c.line.WriteByte(' ')
+			c.space = false
+		}
+
+		c.line.WriteRune(r)
+	}
+}
+
+// block ends the current line and requests the number of line breaks before the next text.
+func (c *textConverter) block(n int) {
+	c.flush()
+	c.breakLines(n)
+}
+
+func (c *textConverter) breakLines(n int) {
+	if n > c.sep {
+		c.sep = n
+	}
+}
+
+// flush writes the current line wrapped with the prefixes.
+func (c *textConverter) flush() {
+	line := c.line.String()
+	c.line.Reset()
+	c.space = false
+
+	if c.pre == 0 {
+		line = strings.TrimSpace(line)
+	}
+
+	if line == "" {
+		return
+	}
+
+	if c.pre > 0 {
+		c.writeLine(line)
+
+		return
+	}
+
+	for _, l := range wrapText(line, textLineLength-utf8.RuneCountInString(c.prefix())) {
+		c.writeLine(l)
+	}
+}
+
+// prefix returns the prefix of the next line.
+func (c *textConverter) prefix() string {
+	p := strings.Repeat("> ", c.quotes)
+	if len(c.lists) > 1 {
+		p += strings.Repeat("  ", len(c.lists)-1)
+	}
+
+	if c.marker != "" {
+		return p + c.marker
+	}
+
+	return p + c.hang
+}
+
+// writeLine writes the line with its prefix after the requested line breaks.
+func (c *textConverter) writeLine(line string) {
+	if c.out.Len() > 0 && c.sep > 1 {
+		// The empty line is quoted only inside of the quotation.
+		quotes := c.quotes
+		if c.lastQuotes < quotes {
+			quotes = c.lastQuotes
+		}
+
+		c.out.WriteString(strings.TrimRight(strings.Repeat("> ", quotes), " ") + "\n")
+	}
+
+	c.sep = 0
+	c.lastQuotes = c.quotes
+
+	c.out.WriteString(strings.TrimRight(c.prefix()+line, " ") + "\n")
+	c.marker = ""
+}
+
+// finish flushes the text and appends the footnotes of the links.
+func (c *textConverter) finish() string {
+	c.pre = 0
+	c.quotes = 0
+	c.lists = nil
+	c.marker, c.hang = "", ""
+	c.block(2)
+
+	for i, link := range c.links {
+		c.writeLine(fmt.Sprintf("[%d] %s", i+1, link))
+	}
+
+	return strings.TrimRight(c.out.String(), "\n")
+}
+
+// wrapText wraps the words of the text into lines of the length, longer words are not broken.
+func wrapText(text string, length int) []string {
+	var (
+		lines []string
+		line  strings.Builder
+		n     int
+	)
+
+	for _, word := range strings.Split(text, " ") {
+		if word == "" {
+			continue
+		}
+
+		wn := utf8.RuneCountInString(word)
+
+		if n > 0 && n+1+wn > length {
+			lines = append(lines, line.String())
+			line.Reset()
+
+			n = 0
+		}
+
+		if n > 0 {
+			line.WriteByte(' ')
+			n++
+		}
+
+		line.WriteString(word)
+		n += wn
+	}
+
+	if n > 0 {
+		lines = append(lines, line.String())
+	}
+
+	return lines
+}
+
+// NewAlternative returns a multipart/alternative Part of the parts ordered from
+// the plainest to the richest, e.g. the text/plain and the text/html Part.
+func NewAlternative(parts ...*Part) *Part {
+	return newMultipart(mediaTypeAlternative, parts)
+}
+
+// NewAlternativeFromHTML returns a multipart/alternative Part of the HTML Part
+// preceded by a UTF-8 text/plain Part derived from its content, see HTMLToText.
+// The content of the HTML Part is read into memory unless it implements io.Seeker.
+func NewAlternativeFromHTML(html *Part) (*Part, error) {
+	text, err := textFromHTML(html)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAlternative(text, html), nil
+}
+
+// textFromHTML returns a text/plain Part derived from the content of the HTML Part.
+func textFromHTML(html *Part) (*Part, error) {
+	var document []byte
+
+	if html.content != nil {
+		var err error
+		if document, err = html.peekContent(); err != nil {
+			return nil, err
+		}
+	}
+
+	return newTextPart(mediaTypeText, strings.NewReader(HTMLToText(string(document)))), nil
+}
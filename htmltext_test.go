@@ -0,0 +1,165 @@
+package gowl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLToText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{
+			name:     "paragraphs",
+			document: "<p>Hello   <b>Jane</b>,\n</p><p>Thanks &amp; bye.</p>",
+			want:     "Hello Jane,\n\nThanks & bye.",
+		},
+		{
+			name:     "skipped elements",
+			document: "<html><head><title>Title</title><style>p { color: red; }</style></head><body><script>alert('<p>')</script>Text</body></html>",
+			want:     "Text",
+		},
+		{
+			name:     "headings",
+			document: "<h1>Welcome</h1><p>Text</p><h3>Details</h3>",
+			want:     "# Welcome\n\nText\n\n### Details",
+		},
+		{
+			name: "links",
+			document: `Please <a href="https://example.com/confirm">confirm</a>, see <a href="https://example.com">https://example.com</a>` +
+				` or <A HREF="https://example.com/confirm">click here</A>. <a href="#top">Top</a> <a href="mailto:jane@example.com">jane@example.com</a>`,
+			want: "Please confirm [1], see https://example.com or click here [1]. Top\njane@example.com\n\n" +
+				"[1] https://example.com/confirm",
+		},
+		{
+			name:     "lists",
+			document: "<ul><li>First</li><li>Second<ol><li>One</li><li>Two</li></ol></li><li>Third</li></ul><p>After</p>",
+			want:     "* First\n* Second\n  1. One\n  2. Two\n* Third\n\nAfter",
+		},
+		{
+			name:     "table",
+			document: "<table><tr><th>Item</th><th>Price</th></tr>\n<tr>\n<td>Cheese</td>\n<td>$5</td>\n</tr></table>",
+			want:     "Item | Price\nCheese | $5",
+		},
+		{
+			name:     "blockquote",
+			document: "<p>Jane wrote:</p><blockquote><p>First</p><p>Second</p></blockquote>",
+			want:     "Jane wrote:\n\n> First\n>\n> Second",
+		},
+		{
+			name:     "preformatted",
+			document: "<pre>func main() {\n    fmt.Println(\"&lt;hi&gt;\")\n}</pre>",
+			want:     "func main() {\n    fmt.Println(\"<hi>\")\n}",
+		},
+		{
+			name:     "line breaks",
+			document: "Line<br>next<br/><br />paragraph<hr>end",
+			want:     "Line\nnext\n\nparagraph\n\n--------------------\n\nend",
+		},
+		{
+			name:     "image",
+			document: `<img src="cid:logo" alt="Example Inc."> Newsletter`,
+			want:     "Example Inc. Newsletter",
+		},
+		{
+			name: "wrapped",
+			document: "<p>This paragraph is long enough to be wrapped at seventy six characters, " +
+				"which is the usual length of the lines of plain text messages.</p><ul><li>A list item which is " +
+				"long enough to be wrapped keeps the indentation of its text.</li></ul>",
+			want: "This paragraph is long enough to be wrapped at seventy six characters, which\n" +
+				"is the usual length of the lines of plain text messages.\n\n" +
+				"* A list item which is long enough to be wrapped keeps the indentation of\n" +
+				"  its text.",
+		},
+		{
+			name:     "entities",
+			document: "<p>Caf&eacute; &lt;b&gt;&nbsp;&#x263A; &amp;amp</p>",
+			want:     "Café <b> ☺ &amp",
+		},
+		{
+			name:     "comments",
+			document: "<p>Shown<!-- <p>hidden</p> --> text</p><!-- unterminated <p>",
+			want:     "Shown text",
+		},
+		{
+			name:     "raw text elements",
+			document: "<script>document.write('</p><p>')</script><style>p::after { content: '<br>' }</style><textarea>&lt;b&gt; <i>typed</i></textarea>",
+			want:     "<b> <i>typed</i>",
+		},
+		{
+			name:     "malformed",
+			document: "a < b and <p class=\"x y\" data-x=1>c</p",
+			want:     "a < b and\n\nc",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, gowl.HTMLToText(tt.document))
+		})
+	}
+}
+
+func TestNewAlternativeFromHTML(t *testing.T) {
+	t.Parallel()
+
+	html := gowl.NewPart(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/html", `charset="UTF-8"`})}),
+		strings.NewReader("<h1>Hello</h1><p>World</p>"),
+		nil,
+	)
+
+	p, err := gowl.NewAlternativeFromHTML(html)
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative[text/plain text/html]", partTree(t, p))
+	require.Equal(t, "# Hello\n\nWorld", partContent(t, p.Parts()[0]))
+	require.Equal(t, "<h1>Hello</h1><p>World</p>", partContent(t, p.Parts()[1]))
+}
+
+func TestMessageBuilder_TextFromHTML(t *testing.T) {
+	t.Parallel()
+
+	msg, err := gowl.NewMessageBuilder().
+		From("john.doe@example.com").
+		HTML(`<p>Hello <a href="https://example.com">there</a></p>`).
+		TextFromHTML(true).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative[text/plain text/html]", partTree(t, msg.RootPart()))
+	require.Equal(t, "Hello there [1]\n\n[1] https://example.com", partContent(t, msg.RootPart().Parts()[0]))
+
+	msg, err = gowl.NewMessageBuilder().
+		From("john.doe@example.com").
+		Text("Custom").
+		HTML("<p>Hello</p>").
+		TextFromHTML(true).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, "Custom", partContent(t, msg.RootPart().Parts()[0]))
+
+	// The text is derived from the HTML of every Build.
+	b := gowl.NewMessageBuilder().From("john.doe@example.com").HTML("<p>Hello</p>").TextFromHTML(true)
+
+	msg, err = b.Build()
+	require.NoError(t, err)
+	require.Equal(t, "Hello", partContent(t, msg.RootPart().Parts()[0]))
+
+	msg, err = b.HTML("<p>Bye</p>").Build()
+	require.NoError(t, err)
+	require.Equal(t, "Bye", partContent(t, msg.RootPart().Parts()[0]))
+
+	msg, err = gowl.NewMessageBuilder().From("john.doe@example.com").HTML("<p>Hello</p>").Build()
+	require.NoError(t, err)
+	require.Equal(t, "text/html", partTree(t, msg.RootPart()))
+}
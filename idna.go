@@ -0,0 +1,121 @@
+package gowl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Error codes returned by failures to convert internationalized addresses.
+var (
+	ErrInvalidDomain     = errors.New("the domain is not a valid internationalized domain name")
+	ErrNonASCIILocalPart = errors.New("the local part of the address contains non-ASCII characters")
+)
+
+// acePrefix is the prefix of the ASCII labels of internationalized domain names.
+const acePrefix = "xn--"
+
+// isASCII reports whether s contains only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DomainToASCII converts the internationalized domain name to its ASCII form by
+// the lookup profile of UTS #46, e.g. "例子.中国" becomes "xn--fsqu00a.xn--fiqs8s".
+// The name is normalized and mapped, so the composed and decomposed forms of a
+// character convert to the same label, and the labels are validated. Ideographic
+// full stops are accepted as label separators. ASCII names without punycode labels
+// are returned unchanged.
+func DomainToASCII(domain string) (string, error) {
+	if isASCII(domain) && !hasACELabel(domain) {
+		return domain, checkDomainLength(domain, domain)
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidDomain, domain, err)
+	}
+
+	return ascii, checkDomainLength(domain, ascii)
+}
+
+// DomainToUnicode converts the ASCII labels with the "xn--" prefix of the domain name to Unicode.
+func DomainToUnicode(domain string) (string, error) {
+	if err := checkDomainLength(domain, domain); err != nil {
+		return "", err
+	}
+
+	if !hasACELabel(domain) {
+		return domain, nil
+	}
+
+	u, err := idna.Lookup.ToUnicode(domain)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidDomain, domain, err)
+	}
+
+	return u, nil
+}
+
+// AddressToASCII converts the domain of the addr-spec to its ASCII form. It returns
+// ErrNonASCIILocalPart if the local part of the address is not ASCII, such an address
+// can only be delivered by a server with the SMTPUTF8 extension.
+func AddressToASCII(address string) (string, error) {
+	i := strings.LastIndexByte(address, '@')
+	if i < 0 {
+		if !isASCII(address) {
+			return "", fmt.Errorf("%w: %s", ErrNonASCIILocalPart, address)
+		}
+
+		return address, nil
+	}
+
+	if !isASCII(address[:i]) {
+		return "", fmt.Errorf("%w: %s", ErrNonASCIILocalPart, address)
+	}
+
+	domain, err := DomainToASCII(address[i+1:])
+	if err != nil {
+		return "", err
+	}
+
+	return address[:i+1] + domain, nil
+}
+
+// hasACELabel reports whether a label of the domain name has the "xn--" prefix.
+func hasACELabel(domain string) bool {
+	for _, l := range strings.Split(domain, ".") {
+		if len(l) >= len(acePrefix) && strings.EqualFold(l[:len(acePrefix)], acePrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDomainLength validates the lengths of the labels of the ASCII form of the
+// domain name, the empty last label is the root of a fully qualified name.
+func checkDomainLength(domain, ascii string) error {
+	labels := strings.Split(ascii, ".")
+
+	for i, l := range labels {
+		if l == "" && (i < len(labels)-1 || i == 0) || len(l) > 63 {
+			return fmt.Errorf("%w: %s", ErrInvalidDomain, domain)
+		}
+	}
+
+	if len(ascii) > 253 {
+		return fmt.Errorf("%w: %s", ErrInvalidDomain, domain)
+	}
+
+	return nil
+}
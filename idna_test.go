@@ -0,0 +1,147 @@
+package gowl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainToASCII(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		domain  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "ascii",
+			domain: "mail.example.com",
+			want:   "mail.example.com",
+		},
+		{
+			name:   "chinese",
+			domain: "例子.中国",
+			want:   "xn--fsqu00a.xn--fiqs8s",
+		},
+		{
+			name:   "mixed label",
+			domain: "München.de",
+			want:   "xn--mnchen-3ya.de",
+		},
+		{
+			name:   "ideographic full stop",
+			domain: "例子。中国",
+			want:   "xn--fsqu00a.xn--fiqs8s",
+		},
+		{
+			name:   "rfc 3492 sample",
+			domain: "ليهمابتكلموشعربي؟",
+			want:   "xn--egbpdaj6bu4bxfgehfvwxn",
+		},
+		{
+			name:   "fully qualified",
+			domain: "bücher.example.",
+			want:   "xn--bcher-kva.example.",
+		},
+		{
+			name:   "decomposed",
+			domain: "bu\u0308cher.example",
+			want:   "xn--bcher-kva.example",
+		},
+		{
+			name:   "upper case",
+			domain: "BÜCHER.example",
+			want:   "xn--bcher-kva.example",
+		},
+		{
+			name:   "underscore",
+			domain: "mail_relay.example.com",
+			want:   "mail_relay.example.com",
+		},
+		{
+			name:    "leading hyphen",
+			domain:  "-bücher.example",
+			wantErr: gowl.ErrInvalidDomain,
+		},
+		{
+			name:    "disallowed code point",
+			domain:  "bücher\u2028.example",
+			wantErr: gowl.ErrInvalidDomain,
+		},
+		{
+			name:    "empty label",
+			domain:  "example..com",
+			wantErr: gowl.ErrInvalidDomain,
+		},
+		{
+			name:    "empty",
+			domain:  "",
+			wantErr: gowl.ErrInvalidDomain,
+		},
+		{
+			name:    "long label",
+			domain:  strings.Repeat("ü", 60) + ".de",
+			wantErr: gowl.ErrInvalidDomain,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := gowl.DomainToASCII(tt.domain)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+
+			domain, err := gowl.DomainToUnicode(got)
+			require.NoError(t, err)
+			want, err := gowl.DomainToASCII(domain)
+			require.NoError(t, err)
+			require.Equal(t, got, want)
+		})
+	}
+}
+
+func TestDomainToUnicode(t *testing.T) {
+	t.Parallel()
+
+	got, err := gowl.DomainToUnicode("XN--Mnchen-3ya.de")
+	require.NoError(t, err)
+	require.Equal(t, "münchen.de", got)
+
+	_, err = gowl.DomainToUnicode("xn--mnchen-3y!.de")
+	require.ErrorIs(t, err, gowl.ErrInvalidDomain)
+
+	_, err = gowl.DomainToUnicode("xn--mnchen-3.de")
+	require.ErrorIs(t, err, gowl.ErrInvalidDomain)
+}
+
+func TestAddressToASCII(t *testing.T) {
+	t.Parallel()
+
+	got, err := gowl.AddressToASCII("john.doe@例子.中国")
+	require.NoError(t, err)
+	require.Equal(t, "john.doe@xn--fsqu00a.xn--fiqs8s", got)
+
+	got, err = gowl.AddressToASCII("postmaster")
+	require.NoError(t, err)
+	require.Equal(t, "postmaster", got)
+
+	_, err = gowl.AddressToASCII("用户@example.com")
+	require.ErrorIs(t, err, gowl.ErrNonASCIILocalPart)
+
+	_, err = gowl.AddressToASCII("john.doe@example..com")
+	require.ErrorIs(t, err, gowl.ErrInvalidDomain)
+}
@@ -0,0 +1,267 @@
+package gowl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Error codes returned by failures to resolve the Content-ID references.
+var (
+	ErrNoContentID      = errors.New("the Header has no Content-ID field")
+	ErrMissingContentID = errors.New("the HTML references a Content-ID which no inline Part has")
+)
+
+// contentIDDomain is the right side of the generated Content-IDs.
+const contentIDDomain = "gowl.local"
+
+// cidAttributes are the HTML attributes whose values are URLs of embedded resources or links.
+var cidAttributes = []string{"src", "href", "background"}
+
+// cidURL matches the cid URLs (RFC 2392) referenced by CSS, e.g. url(cid:logo).
+var cidURL = regexp.MustCompile(`(?i)url\(\s*["']?cid:([^\s"'()]+)`)
+
+// NewContentID generates a random globally unique Content-ID.
+func NewContentID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate Content-ID: %w", err)
+	}
+
+	return hex.EncodeToString(b) + "@" + contentIDDomain, nil
+}
+
+// NewInline returns an inline Part with the given filename and a generated
+// Content-ID the HTML references it by, e.g. <img src="cid:...">. The Content-ID
+// is returned by the ContentID method of the Part header. The media type and the
+// encoding are determined as described in NewAttachment.
+func NewInline(filename string, content io.Reader) (*Part, error) {
+	p, err := newFilePart(DispositionInline, filename, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, setContentID(p)
+}
+
+// NewInlineFile returns an inline Part with the content of the file at the path,
+// see NewInline and NewAttachmentFile.
+func NewInlineFile(name string) (*Part, error) {
+	p, err := newLazyFilePart(DispositionInline, filepath.Base(name), func() (fs.File, error) {
+		return os.Open(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, setContentID(p)
+}
+
+// NewInlineFS returns an inline Part with the content of the named file of the file system.
+func NewInlineFS(fsys fs.FS, name string) (*Part, error) {
+	p, err := newLazyFilePart(DispositionInline, path.Base(name), func() (fs.File, error) {
+		return fsys.Open(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, setContentID(p)
+}
+
+// setContentID sets a generated Content-ID of the Part.
+func setContentID(p *Part) error {
+	id, err := NewContentID()
+	if err != nil {
+		return err
+	}
+
+	p.header.SetContentID(id)
+
+	return nil
+}
+
+// NewRelated returns a multipart/related Part (RFC 2387) of the root Part, usually
+// the HTML body, followed by the inline parts it references.
+func NewRelated(root *Part, inline ...*Part) *Part {
+	p := newMultipart(mediaTypeRelated, append([]*Part{root}, inline...))
+
+	if mediaType, _, err := root.header.ContentType(); err == nil {
+		p.header.Get("Content-Type").SetParam("type", mediaType)
+	}
+
+	return p
+}
+
+// ContentID returns the Content-ID of the Header without the angle brackets.
+func (h *Header) ContentID() (string, error) {
+	f := h.Get("Content-ID")
+	if f == nil {
+		return "", ErrNoContentID
+	}
+
+	if len(f.values) == 0 {
+		return "", ErrNoValues
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(f.values[0]), "<"), ">"), nil
+}
+
+// SetContentID replaces the Content-ID field with the given identifier enclosed in angle brackets.
+func (h *Header) SetContentID(id string) {
+	h.Set("Content-ID", []string{"<" + id + ">"})
+}
+
+// ValidateContentIDs verifies that every cid URL referenced in the text/html parts
+// of the Part and its nested parts matches the Content-ID of a Part. It returns
+// ErrMissingContentID listing the unmatched references and the Content-IDs of the
+// inline parts which are not referenced. The unused inline parts are not an error,
+// but most mail clients show them as attachments.
+//
+// The HTML contents which do not implement io.Seeker are read into memory.
+func (p *Part) ValidateContentIDs() ([]string, error) {
+	var ids, refs []string
+
+	if err := p.collectContentIDs(&ids, &refs); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+
+	for _, ref := range refs {
+		if !containsString(ids, ref) && !containsString(missing, ref) {
+			missing = append(missing, ref)
+		}
+	}
+
+	var unused []string
+
+	for _, id := range ids {
+		if !containsString(refs, id) {
+			unused = append(unused, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		return unused, fmt.Errorf("%w: %s", ErrMissingContentID, strings.Join(missing, ", "))
+	}
+
+	return unused, nil
+}
+
+// collectContentIDs appends the Content-IDs of the inline parts to ids and the cid
+// references of the HTML parts to refs.
+func (p *Part) collectContentIDs(ids, refs *[]string) error {
+	if id, err := p.header.ContentID(); err == nil && id != "" {
+		*ids = append(*ids, id)
+	}
+
+	if mediaType, _, err := p.header.ContentType(); err == nil && mediaType == mediaTypeHTML && p.content != nil {
+		html, err := p.peekContent()
+		if err != nil {
+			return err
+		}
+
+		*refs = append(*refs, htmlContentIDs(string(html))...)
+	}
+
+	for _, sub := range p.parts {
+		if err := sub.collectContentIDs(ids, refs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// htmlContentIDs returns the cid references of the HTML document. They are the values
+// of the src, href and background attributes with the cid scheme and the cid URLs of
+// the style attributes and the <style> elements.
+func htmlContentIDs(document string) []string {
+	var refs []string
+
+	addRef := func(id string) {
+		ref, err := url.PathUnescape(id)
+		if err != nil {
+			ref = id
+		}
+
+		refs = append(refs, ref)
+	}
+
+	addCSS := func(css string) {
+		for _, m := range cidURL.FindAllStringSubmatch(css, -1) {
+			addRef(m[1])
+		}
+	}
+
+	tokens := tokenizeHTML(document)
+
+	for i, t := range tokens {
+		switch t.typ {
+		case htmlStartTag, htmlSelfClosingTag:
+			for _, name := range cidAttributes {
+				if v, ok := t.attr(name); ok {
+					if v = strings.TrimSpace(v); len(v) > 4 && strings.EqualFold(v[:4], "cid:") {
+						addRef(v[4:])
+					}
+				}
+			}
+
+			if style, ok := t.attr("style"); ok {
+				addCSS(style)
+			}
+		case htmlText:
+			if i > 0 && tokens[i-1].typ == htmlStartTag && tokens[i-1].data == "style" {
+				addCSS(t.data)
+			}
+		}
+	}
+
+	return refs
+}
+
+// peekContent reads the content of the Part and rewinds it, contents which
+// do not implement io.Seeker are read into memory.
+func (p *Part) peekContent() ([]byte, error) {
+	if err := p.bufferContent(); err != nil {
+		return nil, err
+	}
+
+	s := p.content.(io.ReadSeeker)
+
+	offset, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seek part content: %w", err)
+	}
+
+	data, err := io.ReadAll(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read part content: %w", err)
+	}
+
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek part content: %w", err)
+	}
+
+	return data, nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,191 @@
+package gowl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInline(t *testing.T) {
+	t.Parallel()
+
+	p, err := gowl.NewInline("logo.png", bytes.NewReader(pngHeader))
+	require.NoError(t, err)
+
+	id, err := p.Header().ContentID()
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9a-f]{32}@gowl\.local$`, id)
+	require.Equal(t, "<"+id+">", p.Header().Get("content-id").Values()[0])
+
+	disposition, _, err := p.Header().ContentDisposition()
+	require.NoError(t, err)
+	require.Equal(t, gowl.DispositionInline, disposition)
+
+	other, err := gowl.NewInlineFS(fstest.MapFS{"logo.png": {Data: pngHeader}}, "logo.png")
+	require.NoError(t, err)
+
+	otherID, err := other.Header().ContentID()
+	require.NoError(t, err)
+	require.NotEqual(t, id, otherID)
+
+	_, err = gowl.NewHeader(nil).ContentID()
+	require.ErrorIs(t, err, gowl.ErrNoContentID)
+}
+
+func TestNewRelated(t *testing.T) {
+	t.Parallel()
+
+	logo, err := gowl.NewInline("logo.png", bytes.NewReader(pngHeader))
+	require.NoError(t, err)
+
+	html := gowl.NewPart(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/html"})}),
+		strings.NewReader("<img>"),
+		nil,
+	)
+
+	p := gowl.NewRelated(html, logo)
+
+	mediaType, params, err := p.Header().ContentType()
+	require.NoError(t, err)
+	require.Equal(t, "multipart/related", mediaType)
+	require.Equal(t, map[string]string{"type": "text/html"}, params)
+	require.Equal(t, []*gowl.Part{html, logo}, p.Parts())
+}
+
+func TestPart_ValidateContentIDs(t *testing.T) {
+	t.Parallel()
+
+	inline := func(id string) *gowl.Part {
+		p, err := gowl.NewInline(id+".png", bytes.NewReader(pngHeader))
+		require.NoError(t, err)
+		p.Header().SetContentID(id)
+
+		return p
+	}
+
+	html := func(content string) *gowl.Part {
+		return gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/html", `charset="UTF-8"`})}),
+			strings.NewReader(content),
+			nil,
+		)
+	}
+
+	tests := []struct {
+		name       string
+		part       *gowl.Part
+		wantUnused []string
+		wantErr    string
+	}{
+		{
+			name: "all referenced",
+			part: gowl.NewRelated(
+				html(`<img src="cid:logo"><img src='CID:banner%40example.com'>`),
+				inline("logo"),
+				inline("banner@example.com"),
+			),
+		},
+		{
+			name: "unused",
+			part: gowl.NewRelated(
+				html(`<img src="cid:logo">`),
+				inline("logo"),
+				inline("banner"),
+			),
+			wantUnused: []string{"banner"},
+		},
+		{
+			name: "missing",
+			part: gowl.NewRelated(
+				html(`<img src="cid:logo"><img src="cid:banner"><div style="background: url(cid:banner)">`),
+				inline("footer"),
+			),
+			wantUnused: []string{"footer"},
+			wantErr:    "banner",
+		},
+		{
+			name: "cid in text",
+			part: gowl.NewRelated(
+				html(`<p>Lucid:Pro and cid:banner are text</p><a href=" CID:logo ">logo</a>`),
+				inline("logo"),
+			),
+		},
+		{
+			name: "style element",
+			part: gowl.NewRelated(
+				html(`<style>div { background: url( "cid:banner" ) }</style><!-- <img src="cid:logo"> --><div>x</div>`),
+				inline("logo"),
+			),
+			wantUnused: []string{"logo"},
+			wantErr:    "banner",
+		},
+		{
+			name: "nested",
+			part: gowl.NewPart(
+				gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/mixed"})}),
+				nil,
+				[]*gowl.Part{gowl.NewRelated(html(`<img src="cid:logo">`), inline("logo"))},
+			),
+		},
+		{
+			name: "no html",
+			part: gowl.NewPart(
+				gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}),
+				strings.NewReader("cid:logo"),
+				nil,
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			unused, err := tt.part.ValidateContentIDs()
+			require.Equal(t, tt.wantUnused, unused)
+
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, gowl.ErrMissingContentID)
+				require.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			// The HTML content is rewound after the validation.
+			rendered, err := tt.part.Render()
+			require.NoError(t, err)
+			require.Contains(t, string(rendered), "cid:")
+		})
+	}
+}
+
+func TestMessageBuilder_Embed(t *testing.T) {
+	t.Parallel()
+
+	msg, err := gowl.NewMessageBuilder().
+		From("john.doe@example.com").
+		HTML(`<img src="cid:logo">`).
+		Embed("logo", "logo.png", bytes.NewReader(pngHeader)).
+		EmbedFS("", fstest.MapFS{"banner.png": {Data: pngHeader}}, "banner.png").
+		Build()
+	require.NoError(t, err)
+
+	unused, err := msg.RootPart().ValidateContentIDs()
+	require.NoError(t, err)
+	require.Len(t, unused, 1)
+
+	_, err = gowl.NewMessageBuilder().
+		From("john.doe@example.com").
+		HTML(`<img src="cid:logo">`).
+		Build()
+	require.ErrorIs(t, err, gowl.ErrMissingContentID)
+}
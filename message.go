@@ -3,6 +3,7 @@ package gowl
 import (
 	"bytes"
 	"fmt"
+	"io"
 )
 
 // Message represents an SMTP message.
@@ -39,27 +40,42 @@ func (m *Message) SetHeader(header *Header) {
 	m.header = header
 }
 
-// SetHeader replaces the part at the root of the Message with the given Part.
+// SetRootPart replaces the part at the root of the Message with the given Part.
 func (m *Message) SetRootPart(rootPart *Part) {
 	m.rootPart = rootPart
 }
 
-// Render renders the message into bytes in an SMTP format.
+// Render renders the message into bytes in an SMTP format. The fields of the Message
+// header are followed by the root part, all lines are terminated by CRLF.
 func (m *Message) Render() ([]byte, error) {
 	buf := bytes.Buffer{}
 
-	head, err := m.header.Render()
-	if err != nil {
-		return nil, fmt.Errorf("failed to render message header: %w", err)
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, err
 	}
 
-	root, err := m.rootPart.Render()
-	if err != nil {
-		return nil, fmt.Errorf("failed to render message root part: %w", err)
+	return buf.Bytes(), nil
+}
+
+// WriteTo streams the rendered message in an SMTP format to w. Unlike Render,
+// it does not hold the whole message in memory, the contents of the parts are
+// copied to w as they are read. It returns the number of bytes written.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+
+	if _, err := m.header.WriteTo(cw); err != nil {
+		return cw.n, fmt.Errorf("failed to render message header: %w", err)
 	}
 
-	buf.Write(head)
-	buf.Write(root)
+	if cw.n > 0 {
+		if _, err := cw.Write(crlf); err != nil {
+			return cw.n, fmt.Errorf("failed to render message header: %w", err)
+		}
+	}
 
-	return buf.Bytes(), nil
+	if _, err := m.rootPart.WriteTo(cw); err != nil {
+		return cw.n, fmt.Errorf("failed to render message root part: %w", err)
+	}
+
+	return cw.n, nil
 }
@@ -1,6 +1,7 @@
 package gowl_test
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 
@@ -135,19 +136,18 @@ func TestMessage_Render(t *testing.T) {
 					},
 				),
 			},
-			want: []byte(`From: Johny <john.smith@example.com>
-To: David Doe <david.doe@example.com>Content-Type: multipart/alternative; boundary="part_12345"
+			want: crlf(`From: Johny <john.smith@example.com>
+To: David Doe <david.doe@example.com>
+Content-Type: multipart/alternative; boundary="part_12345"
 
 --part_12345
 Content-Type: text/plain
 
 This is a test message.
-
 --part_12345
 Content-Type: text/html
 
 <div dir="ltr">This is a test message.</div>
-
 --part_12345--`),
 		},
 		{
@@ -194,7 +194,7 @@ Content-Type: text/html
 				Root: gowl.NewPart(
 					gowl.NewHeader(
 						[]*gowl.Field{
-							gowl.NewField("Content-Type", []string{"multipart/alternative"}),
+							gowl.NewField("Content-Type", nil),
 						},
 					),
 					nil,
@@ -232,3 +232,22 @@ Content-Type: text/html
 		})
 	}
 }
+
+func TestMessage_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	want, err := testMessage().Render()
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	n, err := testMessage().WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(want)), n)
+	require.Equal(t, string(want), buf.String())
+
+	_, err = testMessage().WriteTo(&limitWriter{n: 10})
+	require.ErrorIs(t, err, ErrInvalidWriter)
+
+	_, err = testMessage().WriteTo(&limitWriter{n: 150})
+	require.ErrorIs(t, err, ErrInvalidWriter)
+}
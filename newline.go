@@ -0,0 +1,121 @@
+package gowl
+
+import (
+	"io"
+)
+
+// crlf is the canonical line break of the SMTP data.
+var crlf = []byte{'\r', '\n'}
+
+// countWriter counts the bytes written to the underlying writer.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+
+	return n, err
+}
+
+// crlfWriter converts bare LF and bare CR line breaks to CRLF.
+type crlfWriter struct {
+	w  io.Writer
+	cr bool
+}
+
+// newCRLFWriter returns a writer which normalizes line breaks of the written
+// data to CRLF before writing them to w.
+func newCRLFWriter(w io.Writer) *crlfWriter {
+	return &crlfWriter{w: w}
+}
+
+func (cw *crlfWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, 0, len(p)+len(p)/32+2)
+
+	for _, b := range p {
+		switch {
+		case b == '\n' && cw.cr:
+			// The CRLF was already written with the CR.
+		case b == '\n' || b == '\r':
+			buf = append(buf, crlf...)
+		default:
+			buf = append(buf, b)
+		}
+
+		cw.cr = b == '\r'
+	}
+
+	if _, err := cw.w.Write(buf); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// lfWriter converts CRLF line breaks to LF.
+type lfWriter struct {
+	w  io.Writer
+	cr bool
+}
+
+// NewLFWriter returns a writer which converts CRLF line breaks of the written
+// data to bare LF before writing them to w. It is useful to store rendered
+// messages in local files with Unix line endings. A CR at the end of a write
+// is held back until the next write or Close, Close does not close w.
+func NewLFWriter(w io.Writer) io.WriteCloser {
+	return &lfWriter{w: w}
+}
+
+func (lw *lfWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	buf := make([]byte, 0, len(p)+1)
+
+	if lw.cr {
+		if p[0] != '\n' {
+			buf = append(buf, '\r')
+		}
+
+		lw.cr = false
+	}
+
+	for i, b := range p {
+		if b == '\r' {
+			if i == len(p)-1 {
+				lw.cr = true
+
+				continue
+			}
+
+			if p[i+1] == '\n' {
+				continue
+			}
+		}
+
+		buf = append(buf, b)
+	}
+
+	if _, err := lw.w.Write(buf); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close writes the held back CR, if any.
+func (lw *lfWriter) Close() error {
+	if !lw.cr {
+		return nil
+	}
+
+	lw.cr = false
+
+	_, err := lw.w.Write([]byte{'\r'})
+
+	return err
+}
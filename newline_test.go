@@ -0,0 +1,77 @@
+package gowl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLFWriter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{
+			name:   "crlf",
+			chunks: []string{"From: John\r\nTo: David\r\n\r\nBody."},
+			want:   "From: John\nTo: David\n\nBody.",
+		},
+		{
+			name:   "split crlf",
+			chunks: []string{"Line one.\r", "\nLine two.\r", "", "\n"},
+			want:   "Line one.\nLine two.\n",
+		},
+		{
+			name:   "bare cr",
+			chunks: []string{"One\rTwo\r", "Three\r"},
+			want:   "One\rTwo\rThree\r",
+		},
+		{
+			name:   "bare lf",
+			chunks: []string{"One\nTwo"},
+			want:   "One\nTwo",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.Buffer{}
+			w := gowl.NewLFWriter(&buf)
+
+			for _, c := range tt.chunks {
+				n, err := w.Write([]byte(c))
+				require.NoError(t, err)
+				require.Equal(t, len(c), n)
+			}
+
+			require.NoError(t, w.Close())
+			require.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestNewLFWriter_Message(t *testing.T) {
+	t.Parallel()
+
+	data, err := testMessage().Render()
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	w := gowl.NewLFWriter(&buf)
+
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NotContains(t, buf.String(), "\r")
+	require.Equal(t, strings.ReplaceAll(string(data), "\r\n", "\n"), buf.String())
+}
@@ -0,0 +1,355 @@
+package gowl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxParamLength is the maximum length of a parameter value before it is split
+// into RFC 2231 continuations, so the parameters can be folded into short lines.
+const maxParamLength = 60
+
+// Params returns the parameters of the Field, e.g. the charset of a Content-Type
+// or the filename of a Content-Disposition, keyed by their lower-cased names. The
+// values are unquoted and RFC 2231 continuations and charset-encoded values as well
+// as RFC 2047 encoded words are decoded to UTF-8.
+func (f *Field) Params() map[string]string {
+	params := map[string]string{}
+	sections := map[string][]paramSection{}
+
+	for _, v := range f.values {
+		for _, p := range splitParams(v) {
+			attr, value, ok := parseParam(p)
+			if !ok {
+				continue
+			}
+
+			name, section, ok := splitSection(attr)
+			if !ok {
+				continue
+			}
+
+			sections[name] = append(sections[name], section.with(value))
+		}
+	}
+
+	for name, ss := range sections {
+		params[name] = joinSections(ss)
+	}
+
+	return params
+}
+
+// SetParam sets the parameter of the Field to the given value and removes all
+// previous occurrences of the parameter. Values which are not printable ASCII are
+// encoded according to RFC 2231 and long values are split into continuations.
+func (f *Field) SetParam(param, value string) {
+	param = strings.ToLower(param)
+
+	var values []string
+
+	for _, v := range f.values {
+		for _, p := range splitParams(v) {
+			if attr, _, ok := parseParam(p); ok {
+				if name, _, ok := splitSection(attr); ok && name == param {
+					continue
+				}
+			}
+
+			values = append(values, p)
+		}
+	}
+
+	f.SetValues(append(values, formatParam(param, value)...))
+}
+
+// splitParams splits the value on semicolons which are outside of quoted strings
+// and comments. The parts are trimmed and empty parts are dropped.
+func splitParams(value string) []string {
+	var (
+		params  []string
+		quoted  bool
+		escaped bool
+		depth   int
+		start   int
+	)
+
+	add := func(v string) {
+		if v = strings.TrimSpace(v); v != "" {
+			params = append(params, v)
+		}
+	}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == ';' && depth == 0:
+			add(value[start:i])
+			start = i + 1
+		}
+	}
+
+	add(value[start:])
+
+	return params
+}
+
+// parseParam splits the parameter "attribute=value" into its lower-cased
+// attribute and its unquoted value. It reports false if p is not a parameter.
+func parseParam(p string) (string, string, bool) {
+	i := strings.IndexByte(p, '=')
+	if i <= 0 {
+		return "", "", false
+	}
+
+	attr := strings.ToLower(strings.TrimSpace(p[:i]))
+	if strings.ContainsAny(attr, " \t\"()<>@,;:\\/[]?=") {
+		return "", "", false
+	}
+
+	return attr, unquote(strings.TrimSpace(p[i+1:])), true
+}
+
+// unquote removes the quotes of the quoted string and its escaping backslashes.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// paramSection is a section of a parameter value split into RFC 2231 continuations.
+type paramSection struct {
+	index    int
+	indexed  bool
+	extended bool
+	value    string
+}
+
+func (s paramSection) with(value string) paramSection {
+	s.value = value
+
+	return s
+}
+
+// splitSection splits the RFC 2231 attribute "name*index*" into the name and its section.
+// It reports false if the index of the section is invalid.
+func splitSection(attr string) (string, paramSection, bool) {
+	s := paramSection{}
+
+	if strings.HasSuffix(attr, "*") {
+		s.extended = true
+		attr = attr[:len(attr)-1]
+	}
+
+	i := strings.IndexByte(attr, '*')
+	if i < 0 {
+		return attr, s, true
+	}
+
+	index, err := strconv.Atoi(attr[i+1:])
+	if err != nil || index < 0 {
+		return "", s, false
+	}
+
+	s.index, s.indexed = index, true
+
+	return attr[:i], s, true
+}
+
+// joinSections joins the sections of a parameter value and decodes it. A value
+// without sections takes precedence over continuations, and an extended value
+// takes precedence over a plain one.
+func joinSections(ss []paramSection) string {
+	var whole []paramSection
+
+	for _, s := range ss {
+		if !s.indexed && (whole == nil || s.extended) {
+			whole = []paramSection{s}
+		}
+	}
+
+	if whole != nil {
+		ss = whole
+	}
+
+	sort.SliceStable(ss, func(i, j int) bool { return ss[i].index < ss[j].index })
+
+	var (
+		b       []byte
+		charset string
+	)
+
+	for i, s := range ss {
+		if !s.extended {
+			b = append(b, s.value...)
+
+			continue
+		}
+
+		v := s.value
+		if i == 0 {
+			// The first extended section starts with "charset'language'".
+			if parts := strings.SplitN(v, "'", 3); len(parts) == 3 {
+				charset, v = parts[0], parts[2]
+			}
+		}
+
+		b = append(b, percentDecode(v)...)
+	}
+
+	value := toUTF8(charset, b)
+	if !ss[0].extended && strings.HasPrefix(value, "=?") {
+		if decoded, err := DecodeHeader(value); err == nil {
+			value = decoded
+		}
+	}
+
+	return value
+}
+
+// percentDecode decodes the %XX escapes of s, invalid escapes are kept.
+func percentDecode(s string) []byte {
+	b := make([]byte, 0, len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			n, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			b = append(b, byte(n))
+			i += 2
+
+			continue
+		}
+
+		b = append(b, s[i])
+	}
+
+	return b
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// toUTF8 converts the bytes in the given charset to UTF-8. Bytes in charsets
+// other than UTF-8, US-ASCII and ISO-8859-1 are kept unchanged.
+func toUTF8(charset string, b []byte) string {
+	if !strings.EqualFold(charset, "ISO-8859-1") {
+		return string(b)
+	}
+
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+
+	return string(r)
+}
+
+// formatParam serializes the parameter into one or more RFC 2231 sections.
+func formatParam(name, value string) []string {
+	ascii := true
+
+	for _, r := range value {
+		if r < ' ' || r >= utf8.RuneSelf || r == 0x7f {
+			ascii = false
+
+			break
+		}
+	}
+
+	if ascii {
+		if len(value) <= maxParamLength {
+			return []string{name + "=" + quote(value)}
+		}
+
+		var params []string
+		for i, chunk := range splitChunks(value, maxParamLength, false) {
+			params = append(params, fmt.Sprintf("%s*%d=%s", name, i, quote(chunk)))
+		}
+
+		return params
+	}
+
+	encoded := "UTF-8''" + percentEncode(value)
+	if len(encoded) <= maxParamLength {
+		return []string{name + "*=" + encoded}
+	}
+
+	var params []string
+	for i, chunk := range splitChunks(encoded, maxParamLength, true) {
+		params = append(params, fmt.Sprintf("%s*%d*=%s", name, i, chunk))
+	}
+
+	return params
+}
+
+// quote returns s as a quoted string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// attributeChars are the characters which are not percent-encoded in RFC 2231 values.
+const attributeChars = "!#$&+-.^_`|~"
+
+// percentEncode encodes all bytes of s except of RFC 2231 attribute characters.
+func percentEncode(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte(attributeChars, c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// splitChunks splits s into chunks of at most n bytes. If escaped is set,
+// the %XX escapes are never split.
+func splitChunks(s string, n int, escaped bool) []string {
+	var chunks []string
+
+	for len(s) > n {
+		i := n
+		if escaped {
+			if j := strings.LastIndexByte(s[:i], '%'); j >= i-2 {
+				i = j
+			}
+		}
+
+		chunks = append(chunks, s[:i])
+		s = s[i:]
+	}
+
+	return append(chunks, s)
+}
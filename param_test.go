@@ -0,0 +1,166 @@
+package gowl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestField_Params(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values []string
+		want   map[string]string
+	}{
+		{
+			name:   "name and filename",
+			values: []string{"attachment", `filename="report.pdf"`, "name=report"},
+			want:   map[string]string{"filename": "report.pdf", "name": "report"},
+		},
+		{
+			name:   "joined values",
+			values: []string{`text/plain; Charset=UTF-8; format=flowed`},
+			want:   map[string]string{"charset": "UTF-8", "format": "flowed"},
+		},
+		{
+			name:   "quoted specials",
+			values: []string{"attachment", `filename="a \"quoted\"; name.txt"`},
+			want:   map[string]string{"filename": `a "quoted"; name.txt`},
+		},
+		{
+			name:   "continuations",
+			values: []string{"attachment", `filename*1="name.txt"`, `filename*0="long "`},
+			want:   map[string]string{"filename": "long name.txt"},
+		},
+		{
+			name:   "extended",
+			values: []string{"attachment", `filename*=UTF-8'de'Gr%C3%BC%C3%9Fe.txt`},
+			want:   map[string]string{"filename": "Grüße.txt"},
+		},
+		{
+			name:   "extended continuations",
+			values: []string{"attachment", `filename*0*=ISO-8859-1''Gr%FC`, `filename*1*=%DFe`, `filename*2=".txt"`},
+			want:   map[string]string{"filename": "Grüße.txt"},
+		},
+		{
+			name:   "extended precedence",
+			values: []string{"attachment", `filename="Grusse.txt"`, `filename*=UTF-8''Gr%C3%BC%C3%9Fe.txt`},
+			want:   map[string]string{"filename": "Grüße.txt"},
+		},
+		{
+			name:   "encoded word",
+			values: []string{"attachment", `filename="=?UTF-8?b?R3LDvMOfZS50eHQ=?="`},
+			want:   map[string]string{"filename": "Grüße.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := gowl.NewField("Content-Disposition", tt.values)
+			require.Equal(t, tt.want, f.Params())
+
+			for name, value := range tt.want {
+				require.Equal(t, value, string(f.Param(strings.ToUpper(name))))
+			}
+		})
+	}
+}
+
+func TestField_SetParam(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values []string
+		param  string
+		value  string
+		want   []string
+	}{
+		{
+			name:   "add",
+			values: []string{"text/plain"},
+			param:  "charset",
+			value:  "UTF-8",
+			want:   []string{"text/plain", `charset="UTF-8"`},
+		},
+		{
+			name:   "replace",
+			values: []string{"attachment; filename*0=old; filename*1=.txt", `name="old.txt"`},
+			param:  "Filename",
+			value:  `new "report".txt`,
+			want:   []string{"attachment", `name="old.txt"`, `filename="new \"report\".txt"`},
+		},
+		{
+			name:   "non-ascii",
+			values: []string{"attachment"},
+			param:  "filename",
+			value:  "Grüße.txt",
+			want:   []string{"attachment", `filename*=UTF-8''Gr%C3%BC%C3%9Fe.txt`},
+		},
+		{
+			name:   "long ascii",
+			values: []string{"attachment"},
+			param:  "filename",
+			value:  strings.Repeat("a", 70) + ".txt",
+			want:   []string{"attachment", `filename*0="` + strings.Repeat("a", 60) + `"`, `filename*1="` + strings.Repeat("a", 10) + `.txt"`},
+		},
+		{
+			name:   "long non-ascii",
+			values: []string{"attachment"},
+			param:  "filename",
+			value:  strings.Repeat("ü", 10) + ".txt",
+			want: []string{
+				"attachment",
+				`filename*0*=UTF-8''` + strings.Repeat("%C3%BC", 8) + "%C3",
+				`filename*1*=%BC` + strings.Repeat("%C3%BC", 1) + ".txt",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := gowl.NewField("Content-Disposition", tt.values)
+			f.SetParam(tt.param, tt.value)
+			require.Equal(t, tt.want, f.Values())
+			require.Equal(t, tt.value, f.Params()[strings.ToLower(tt.param)])
+		})
+	}
+}
+
+func TestField_ParamRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	name := "Отчёт за первый квартал 2021 года (финальная версия).pdf"
+
+	f := gowl.NewField("Content-Disposition", []string{"attachment"})
+	f.SetParam("filename", name)
+
+	msg := gowl.NewMessage(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Subject", []string{"Report"})}),
+		gowl.NewPart(gowl.NewHeader([]*gowl.Field{f}), strings.NewReader("Report"), nil),
+	)
+
+	raw, err := msg.Render()
+	require.NoError(t, err)
+
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		require.LessOrEqual(t, len(line), 78)
+	}
+
+	parsed, err := gowl.ParseMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	require.Equal(t, name, string(parsed.RootPart().Header().Fields()[0].Param("filename")))
+}
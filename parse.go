@@ -0,0 +1,283 @@
+package gowl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInvalidHeader is returned when a header line of a parsed message is malformed.
+var ErrInvalidHeader = errors.New("the header line is not a valid field")
+
+// ParseMessage reads a raw RFC 5322 message and builds its Message. The Content-*
+// fields of the message header are moved to the header of the root part, the other
+// fields are kept in the header of the Message. Header fields are unfolded and the
+// values of structured fields are split on semicolons, the folded lines are kept
+// so unchanged fields are rendered exactly as they were read. Multipart bodies are
+// split on their boundaries into nested parts and the contents of leaf parts are
+// decoded according to their Content-Transfer-Encoding. The contents of unknown
+// encodings or malformed encoded data are not decoded. The encoded bodies are
+// kept as well and rendered verbatim until the content of the part is replaced.
+//
+// Rendering the parsed message reproduces the input with CRLF line breaks if its
+// Content-* fields follow the other fields of the message header.
+func ParseMessage(r io.Reader) (*Message, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	fields, body, err := parseHeader(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message header: %w", err)
+	}
+
+	var header, rootHeader []*Field
+
+	for _, f := range fields {
+		if strings.HasPrefix(strings.ToLower(f.name), "content-") {
+			rootHeader = append(rootHeader, f)
+		} else {
+			header = append(header, f)
+		}
+	}
+
+	root, err := parsePart(NewHeader(rootHeader), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message root part: %w", err)
+	}
+
+	return NewMessage(NewHeader(header), root), nil
+}
+
+// parseHeader parses the header fields at the beginning of data. It returns
+// the fields and the body which follows the empty line after the header.
+func parseHeader(data []byte) ([]*Field, []byte, error) {
+	var (
+		fields []*Field
+		lines  []string
+	)
+
+	flush := func() error {
+		if lines == nil {
+			return nil
+		}
+
+		f, err := parseField(lines)
+		if err != nil {
+			return err
+		}
+
+		fields = append(fields, f)
+		lines = nil
+
+		return nil
+	}
+
+	for len(data) > 0 {
+		line, rest := nextLine(data)
+
+		if line == "" {
+			return fields, rest, flush()
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			if err := flush(); err != nil {
+				return nil, nil, err
+			}
+		} else if lines == nil {
+			return nil, nil, fmt.Errorf("%w: %q", ErrInvalidHeader, line)
+		}
+
+		lines = append(lines, line)
+		data = rest
+	}
+
+	return fields, nil, flush()
+}
+
+// nextLine returns the first line of data without its line break and the data which follows it.
+func nextLine(data []byte) (string, []byte) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return string(data), nil
+	}
+
+	return strings.TrimSuffix(string(data[:i]), "\r"), data[i+1:]
+}
+
+// parseField parses the folded lines of a header field.
+func parseField(lines []string) (*Field, error) {
+	raw := strings.Join(lines, "\r\n")
+
+	i := strings.IndexByte(raw, ':')
+	if i <= 0 || strings.ContainsAny(raw[:i], " \t") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, lines[0])
+	}
+
+	name := raw[:i]
+	value := strings.TrimSpace(strings.ReplaceAll(raw[i+1:], "\r\n", ""))
+
+	f := NewField(name, splitValues(name, value))
+	f.raw = raw
+
+	return f, nil
+}
+
+// splitValues splits the value of a structured field into its semicolon separated
+// values. Values of unstructured and address fields are never split.
+func splitValues(name, value string) []string {
+	if n := strings.ToLower(name); unstructuredFields[n] || addressFields[n] || strings.HasPrefix(n, "x-") {
+		return []string{value}
+	}
+
+	values := splitParams(value)
+	if values == nil {
+		values = []string{""}
+	}
+
+	return values
+}
+
+// parsePart builds a Part of the given header from its raw body.
+func parsePart(header *Header, body []byte) (*Part, error) {
+	if !isMultipart(header) {
+		encoding := header.transferEncoding()
+
+		// A body which can not be decoded is kept as the content, a single
+		// malformed part does not make the whole message unreadable.
+		content, err := decodeContent(body, encoding)
+		if err != nil {
+			content = body
+		}
+
+		p := NewPart(header, bytes.NewReader(content), nil)
+		p.raw = body
+		p.rawEncoding = encoding
+
+		return p, nil
+	}
+
+	boundary, err := header.Boundary()
+	if err != nil {
+		return nil, err
+	}
+
+	preamble, bodies, epilogue := splitMultipart(body, boundary)
+
+	p := NewPart(header, nil, []*Part{})
+	if preamble != nil {
+		p.content = bytes.NewReader(preamble)
+	}
+
+	p.epilogue = epilogue
+
+	for _, b := range bodies {
+		fields, content, err := parseHeader(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sub-part header: %w", err)
+		}
+
+		sub, err := parsePart(NewHeader(fields), content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sub-part: %w", err)
+		}
+
+		p.parts = append(p.parts, sub)
+	}
+
+	return p, nil
+}
+
+// isMultipart reports whether the Content-Type of the Header is multipart.
+func isMultipart(h *Header) bool {
+	mediaType, _, err := h.ContentType()
+
+	return err == nil && strings.HasPrefix(mediaType, "multipart/")
+}
+
+// splitMultipart splits a multipart body into the preamble, the bodies of the
+// parts and the epilogue. The line break before a delimiter line belongs to the
+// delimiter. The preamble is nil if the body starts with a delimiter line. If the
+// closing delimiter is missing, the last part extends to the end of the body.
+func splitMultipart(body, boundary []byte) ([]byte, [][]byte, []byte) {
+	var (
+		preamble []byte
+		bodies   [][]byte
+		start    = -1
+	)
+
+	delim := append([]byte("--"), boundary...)
+
+	for pos := 0; pos < len(body); {
+		end := bytes.IndexByte(body[pos:], '\n')
+		if end < 0 {
+			end = len(body)
+		} else {
+			end += pos + 1
+		}
+
+		line := bytes.TrimRight(body[pos:end], "\r\n")
+
+		if bytes.HasPrefix(line, delim) {
+			rest := bytes.TrimRight(line[len(delim):], " \t")
+			closing := bytes.Equal(rest, []byte("--"))
+
+			if len(rest) == 0 || closing {
+				// The content before the delimiter without the line break.
+				content := body[:pos]
+				if start >= 0 {
+					content = body[start:pos]
+				}
+
+				content = trimLineBreak(content)
+
+				if start >= 0 {
+					bodies = append(bodies, content)
+				} else if pos > 0 {
+					preamble = content
+				}
+
+				if closing {
+					return preamble, bodies, body[pos+len(line):]
+				}
+
+				start = end
+			}
+		}
+
+		pos = end
+	}
+
+	if start >= 0 {
+		bodies = append(bodies, body[start:])
+	}
+
+	return preamble, bodies, nil
+}
+
+// trimLineBreak removes a single trailing line break.
+func trimLineBreak(b []byte) []byte {
+	if bytes.HasSuffix(b, crlf) {
+		return b[:len(b)-2]
+	}
+
+	return bytes.TrimSuffix(b, []byte{'\n'})
+}
+
+// decodeContent decodes the body of a part encoded with the given transfer encoding.
+func decodeContent(body []byte, encoding string) ([]byte, error) {
+	r, err := decoder(bytes.NewReader(body), encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode part content: %w", err)
+	}
+
+	return content, nil
+}
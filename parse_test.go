@@ -0,0 +1,295 @@
+package gowl_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessage(t *testing.T) {
+	t.Parallel()
+
+	raw := crlf(`Received: from mail.example.com (mail.example.com [192.0.2.1])
+	by mx.example.org with ESMTPS id 123456789abcdef;
+	Sat, 13 Mar 2021 07:00:30 -0800 (PST)
+From: =?UTF-8?b?Wm/DqyBNw7xsbGVy?= <zoe@example.com>
+To: John Doe <john.doe@example.org>
+Subject: Report
+MIME-Version: 1.0
+Content-type: multipart/mixed;
+  boundary="outer"
+
+This is a multi-part message in MIME format.
+--outer
+Content-Type: multipart/alternative; boundary=inner
+
+--inner
+Content-Type: text/plain; charset="UTF-8"
+Content-Transfer-Encoding: quoted-printable
+
+Gr=C3=BC=C3=9Fe
+--inner
+Content-Type: text/html; charset="UTF-8"
+
+<p>Hello</p>
+--inner--
+--outer
+Content-Type: text/plain; name="report.txt"
+Content-Transfer-Encoding: base64
+
+VGhpcyBpcyBhIHRlc3QgZmlsZS4K
+--outer--
+Epilogue.
+`)
+
+	msg, err := gowl.ParseMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	got, err := msg.Render()
+	require.NoError(t, err)
+	require.Equal(t, string(raw), string(got))
+
+	fields := msg.Header().Fields()
+	require.Len(t, fields, 5)
+	require.Equal(t, "Received", fields[0].Name())
+	require.Equal(t, []string{
+		"from mail.example.com (mail.example.com [192.0.2.1])\tby mx.example.org with ESMTPS id 123456789abcdef",
+		"Sat, 13 Mar 2021 07:00:30 -0800 (PST)",
+	}, fields[0].Values())
+	require.Equal(t, []string{"=?UTF-8?b?Wm/DqyBNw7xsbGVy?= <zoe@example.com>"}, fields[1].Values())
+	require.Equal(t, []string{"1.0"}, fields[4].Values())
+
+	root := msg.RootPart()
+	require.Equal(t, []string{"multipart/mixed", `boundary="outer"`}, root.Header().Fields()[0].Values())
+	require.Len(t, root.Parts(), 2)
+
+	preamble, err := io.ReadAll(root.Content())
+	require.NoError(t, err)
+	require.Equal(t, "This is a multi-part message in MIME format.", string(preamble))
+
+	alternative := root.Parts()[0]
+	require.Len(t, alternative.Parts(), 2)
+	require.Nil(t, alternative.Content())
+
+	text, err := io.ReadAll(alternative.Parts()[0].Content())
+	require.NoError(t, err)
+	require.Equal(t, "Grüße", string(text))
+
+	html, err := io.ReadAll(alternative.Parts()[1].Content())
+	require.NoError(t, err)
+	require.Equal(t, "<p>Hello</p>", string(html))
+
+	file, err := io.ReadAll(root.Parts()[1].Content())
+	require.NoError(t, err)
+	require.Equal(t, "This is a test file.\n", string(file))
+
+	fields[3].SetValues([]string{"Quarterly report"})
+	got, err = msg.Render()
+	require.NoError(t, err)
+	require.Contains(t, string(got), "\r\nSubject: Quarterly report\r\n")
+}
+
+func TestParseMessage_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	msg := gowl.NewMessage(
+		gowl.NewHeader([]*gowl.Field{
+			gowl.NewField("From", []string{"Zoë Müller <zoe@example.com>"}),
+			gowl.NewField("To", []string{strings.Repeat("John Doe <john.doe@example.org>, ", 3) + "Jane <jane@example.org>"}),
+			gowl.NewField("Subject", []string{"Your order for Zoë is ready"}),
+			gowl.NewField("MIME-Version", []string{"1.0"}),
+		}),
+		gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/mixed"})}),
+			nil,
+			[]*gowl.Part{
+				gowl.NewPart(
+					gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/alternative"})}),
+					nil,
+					textParts(),
+				),
+				gowl.NewPart(
+					gowl.NewHeader([]*gowl.Field{
+						gowl.NewField("Content-Type", []string{"application/octet-stream"}),
+						gowl.NewField("Content-Transfer-Encoding", []string{gowl.EncodingAuto}),
+					}),
+					bytes.NewReader(bytes.Repeat([]byte{0x00, 0xff, 0x10}, 100)),
+					nil,
+				),
+			},
+		),
+	)
+
+	want, err := msg.Render()
+	require.NoError(t, err)
+
+	parsed, err := gowl.ParseMessage(bytes.NewReader(want))
+	require.NoError(t, err)
+
+	got, err := parsed.Render()
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+
+	content, err := io.ReadAll(parsed.RootPart().Parts()[1].Content())
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte{0x00, 0xff, 0x10}, 100), content)
+}
+
+func TestParseMessage_RawBody(t *testing.T) {
+	t.Parallel()
+
+	// The base64 lines are wrapped at 72 columns instead of 76 and
+	// the quoted-printable line is encoded more than necessary.
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x00, 0xff, 0x10}, 60))
+	wrapped := encoded[:72] + "\r\n" + encoded[72:144] + "\r\n" + encoded[144:]
+
+	data := "Subject: Report\r\n" +
+		"Content-Type: multipart/mixed; boundary=b1\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"=48ello Bob\r\n" +
+		"--b1\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		wrapped + "\r\n" +
+		"--b1--"
+
+	parsed, err := gowl.ParseMessage(strings.NewReader(data))
+	require.NoError(t, err)
+
+	got, err := parsed.Render()
+	require.NoError(t, err)
+	require.Equal(t, data, string(got))
+
+	text := parsed.RootPart().Parts()[0]
+
+	content, err := io.ReadAll(text.Content())
+	require.NoError(t, err)
+	require.Equal(t, "Hello Bob", string(content))
+
+	text.SetContent(strings.NewReader("Hello Eve"))
+
+	got, err = parsed.Render()
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(data, "=48ello Bob", "Hello Eve", 1), string(got))
+
+	attachment := parsed.RootPart().Parts()[1]
+	attachment.Header().Get("Content-Transfer-Encoding").SetValues([]string{gowl.EncodingQuotedPrintable})
+
+	got, err = parsed.Render()
+	require.NoError(t, err)
+	require.NotContains(t, string(got), encoded[:72])
+	require.Contains(t, string(got), "=00=FF=10")
+}
+
+func TestParseMessage_MalformedContent(t *testing.T) {
+	t.Parallel()
+
+	data := "Content-Type: multipart/mixed; boundary=b\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"!!!!\r\n" +
+		"--b\r\n" +
+		"Content-Transfer-Encoding: x-uuencode\r\n" +
+		"\r\n" +
+		"begin 644 hello.txt\r\n" +
+		"--b\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"SGVsbG8=\r\n" +
+		"--b--"
+
+	msg, err := gowl.ParseMessage(strings.NewReader(data))
+	require.NoError(t, err)
+
+	got, err := msg.Render()
+	require.NoError(t, err)
+	require.Equal(t, data, string(got))
+
+	for i, want := range []string{"!!!!", "begin 644 hello.txt", "Hello"} {
+		content, err := io.ReadAll(msg.RootPart().Parts()[i].Content())
+		require.NoError(t, err)
+		require.Equal(t, want, string(content))
+	}
+}
+
+func TestParseMessage_LF(t *testing.T) {
+	t.Parallel()
+
+	msg, err := gowl.ParseMessage(strings.NewReader("Subject: Hello\nContent-Type: text/plain\n\nHello,\nworld!\n"))
+	require.NoError(t, err)
+	require.Len(t, msg.Header().Fields(), 1)
+	require.Len(t, msg.RootPart().Header().Fields(), 1)
+
+	got, err := msg.Render()
+	require.NoError(t, err)
+	require.Equal(t, "Subject: Hello\r\nContent-Type: text/plain\r\n\r\nHello,\r\nworld!\r\n", string(got))
+
+	msg, err = gowl.ParseMessage(strings.NewReader("Subject: Hello\n\nHello,\nworld!"))
+	require.NoError(t, err)
+	require.Empty(t, msg.RootPart().Header().Fields())
+
+	got, err = msg.Render()
+	require.NoError(t, err)
+	require.Equal(t, "Subject: Hello\r\n\r\nHello,\r\nworld!", string(got))
+}
+
+func TestParseMessage_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		r       io.Reader
+		wantErr error
+	}{
+		{
+			name:    "invalid reader",
+			r:       errReader{},
+			wantErr: ErrInvalidReader,
+		},
+		{
+			name:    "missing colon",
+			r:       strings.NewReader("Subject Hello\r\n\r\nHello"),
+			wantErr: gowl.ErrInvalidHeader,
+		},
+		{
+			name:    "leading continuation line",
+			r:       strings.NewReader(" Subject: Hello\r\n\r\nHello"),
+			wantErr: gowl.ErrInvalidHeader,
+		},
+		{
+			name:    "invalid sub-part header",
+			r:       strings.NewReader("Content-Type: multipart/mixed; boundary=b\r\n\r\n--b\r\nInvalid\r\n\r\nHello\r\n--b--"),
+			wantErr: gowl.ErrInvalidHeader,
+		},
+		{
+			name:    "missing boundary",
+			r:       strings.NewReader("Content-Type: multipart/mixed\r\n\r\nHello"),
+			wantErr: gowl.ErrNoBoundary,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			msg, err := gowl.ParseMessage(tt.r)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Nil(t, msg)
+		})
+	}
+
+}
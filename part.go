@@ -12,6 +12,14 @@ type Part struct {
 	header  *Header
 	content io.Reader
 	parts   []*Part
+	// epilogue follows the closing boundary of a parsed multipart Part.
+	epilogue []byte
+	// raw is the encoded body of a parsed leaf Part and rawEncoding its transfer encoding.
+	// The body is written verbatim until the content or the encoding is changed.
+	raw         []byte
+	rawEncoding string
+	// inlineCSS moves the <style> rules of an HTML content to the style attributes.
+	inlineCSS bool
 }
 
 // NewPart is a constructor of the Part.
@@ -43,6 +51,12 @@ func (p *Part) Parts() []*Part {
 	return p.parts
 }
 
+// InlineCSS reports whether the stylesheets of the text/html content of the Part
+// are inlined when it is rendered.
+func (p *Part) InlineCSS() bool {
+	return p.inlineCSS
+}
+
 // SetHeader replaces a header of the Part with the given Header.
 func (p *Part) SetHeader(header *Header) {
 	p.header = header
@@ -51,6 +65,7 @@ func (p *Part) SetHeader(header *Header) {
 // SetContent replaces a content of the Part with the given io.Reader.
 func (p *Part) SetContent(content io.Reader) {
 	p.content = content
+	p.raw = nil
 }
 
 // SetParts replaces sub-parts of the Part with the given slice of Parts.
@@ -58,50 +73,145 @@ func (p *Part) SetParts(parts []*Part) {
 	p.parts = parts
 }
 
+// SetInlineCSS sets whether the stylesheets of the text/html content of the Part are
+// inlined into the style attributes of its elements when it is rendered, see InlineCSS.
+// The content of the Part is not modified.
+func (p *Part) SetInlineCSS(inline bool) {
+	p.inlineCSS = inline
+}
+
 // Render renders the content of the Part into bytes. It returns a formatted SMTP message Part.
+// The header is separated from the body by an empty line, all lines are terminated by CRLF
+// and bare LF or CR line breaks of the content are converted to CRLF unless the content
+// is encoded in base64 or sent as binary.
 func (p *Part) Render() ([]byte, error) {
 	buf := bytes.Buffer{}
 
-	head, err := p.header.Render()
-	if err != nil {
-		return nil, fmt.Errorf("failed to render part header: %w", err)
+	if _, err := p.WriteTo(&buf); err != nil {
+		return nil, err
 	}
 
-	buf.Write(head)
+	return buf.Bytes(), nil
+}
 
-	if p.content != nil {
-		buf.Write([]byte{'\n', '\n'})
+// WriteTo streams the rendered Part to w in the same format as Render. The content
+// is encoded according to the Content-Transfer-Encoding field of the Part and copied
+// to w as it is read, so large contents are never held in memory. It returns the
+// number of bytes written.
+//
+// The EncodingAuto is replaced with the cheapest encoding safe for the content,
+// which requires a scan of the content. If a multipart Part has no boundary,
+// a random one is generated and stored in its Content-Type field. If the content
+// implements io.Seeker, it is rewound after it is written, so the Part can be
+// written again.
+func (p *Part) WriteTo(w io.Writer) (int64, error) {
+	if p.inlineCSS {
+		inlined, err := p.inlinedCSS()
+		if err != nil {
+			return 0, err
+		}
 
-		if _, err := buf.ReadFrom(p.content); err != nil {
-			return nil, fmt.Errorf("failed to read part content: %w", err)
+		if inlined != nil {
+			return inlined.WriteTo(w)
 		}
 	}
 
+	cw := &countWriter{w: w}
+
+	// The encoding is resolved before the header is written.
+	if err := p.resolveEncoding(); err != nil {
+		return cw.n, err
+	}
+
+	var boundary []byte
+
 	if p.parts != nil {
-		boundary, err := p.header.Boundary()
-		if err != nil {
-			return nil, fmt.Errorf("failed to retrieve sub-part boundary: %w", err)
+		// The boundary is generated before the header is written.
+		var err error
+		if boundary, err = p.ensureBoundary(); err != nil {
+			return cw.n, fmt.Errorf("failed to retrieve sub-part boundary: %w", err)
+		}
+	}
+
+	if _, err := p.header.WriteTo(cw); err != nil {
+		return cw.n, fmt.Errorf("failed to render part header: %w", err)
+	}
+
+	// The last header line is terminated by CRLF unless the header is empty.
+	if cw.n > 0 {
+		if _, err := cw.Write(crlf); err != nil {
+			return cw.n, err
 		}
+	}
 
-		bStart := append([]byte{'-', '-'}, boundary...)
-		bEnd := append(bStart, '-', '-')
+	if _, err := cw.Write(crlf); err != nil {
+		return cw.n, err
+	}
 
-		for _, p := range p.parts {
-			buf.Write([]byte{'\n', '\n'})
-			buf.Write(bStart)
-			buf.WriteRune('\n')
+	if p.content != nil {
+		if err := p.writeContent(cw); err != nil {
+			return cw.n, err
+		}
+	}
 
-			part, err := p.Render()
-			if err != nil {
-				return nil, fmt.Errorf("failed to render sub-part part: %w", err)
+	if p.parts != nil {
+		delim := append([]byte("\r\n--"), boundary...)
+
+		for i, sub := range p.parts {
+			d := delim
+			if i == 0 && p.content == nil {
+				// The first boundary directly follows the header, the CRLF
+				// before it is already written as the empty line.
+				d = delim[2:]
+			}
+
+			if _, err := cw.Write(append(d, crlf...)); err != nil {
+				return cw.n, err
 			}
 
-			buf.Write(part)
+			if _, err := sub.WriteTo(cw); err != nil {
+				return cw.n, fmt.Errorf("failed to render sub-part part: %w", err)
+			}
+		}
+
+		if _, err := cw.Write(append(delim, '-', '-')); err != nil {
+			return cw.n, err
 		}
 
-		buf.Write([]byte{'\n', '\n'})
-		buf.Write(bEnd)
+		if _, err := cw.Write(p.epilogue); err != nil {
+			return cw.n, err
+		}
 	}
 
-	return buf.Bytes(), nil
+	return cw.n, nil
+}
+
+// writeContent copies the content of the Part to w.
+func (p *Part) writeContent(w io.Writer) error {
+	if encoding := p.header.transferEncoding(); p.raw != nil && encoding == p.rawEncoding {
+		if encoding != EncodingBinary {
+			w = newCRLFWriter(w)
+		}
+
+		if _, err := w.Write(p.raw); err != nil {
+			return fmt.Errorf("failed to write part content: %w", err)
+		}
+
+		return nil
+	}
+
+	if s, ok := p.content.(io.Seeker); ok {
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("failed to seek part content: %w", err)
+		}
+
+		defer func() { _, _ = s.Seek(offset, io.SeekStart) }()
+	}
+
+	if err := writeEncoded(w, p.content, p.header.transferEncoding()); err != nil {
+		return fmt.Errorf("failed to write part content: %w", err)
+	}
+
+	return nil
 }
@@ -1,6 +1,7 @@
 package gowl_test
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"strings"
@@ -18,6 +19,11 @@ func (r errReader) Read([]byte) (int, error) {
 	return 0, ErrInvalidReader
 }
 
+// crlf converts the LF line breaks of the given text to CRLF.
+func crlf(s string) []byte {
+	return []byte(strings.ReplaceAll(s, "\n", "\r\n"))
+}
+
 func TestPart_Reset(t *testing.T) {
 	t.Parallel()
 
@@ -176,18 +182,16 @@ func TestPart_Render(t *testing.T) {
 					),
 				},
 			},
-			want: []byte(`Content-Type: multipart/alternative; boundary="0000000000009c8ab105be4e2cc3"
+			want: crlf(`Content-Type: multipart/alternative; boundary="0000000000009c8ab105be4e2cc3"
 
 --0000000000009c8ab105be4e2cc3
 Content-Type: text/plain; charset="UTF-8"
 
 This is a test message.
-
 --0000000000009c8ab105be4e2cc3
 Content-Type: text/html; charset="UTF-8"
 
 <div dir="ltr">This is a test message.</div>
-
 --0000000000009c8ab105be4e2cc3--`,
 			),
 		},
@@ -220,12 +224,12 @@ Content-Type: text/html; charset="UTF-8"
 								gowl.NewField("Content-Transfer-Encoding", []string{"base64"}),
 							},
 						),
-						strings.NewReader(`VGhpcyBpcyBhIHRlc3QgZmlsZS4K`),
+						strings.NewReader("This is a test file.\n"),
 						nil,
 					),
 				},
 			},
-			want: []byte(`Content-Type: multipart/mixed; boundary="0000000000001d296f05be7539bd"
+			want: crlf(`Content-Type: multipart/mixed; boundary="0000000000001d296f05be7539bd"
 
 --0000000000001d296f05be7539bd
 Content-Type: multipart/alternative; boundary="0000000000001d296c05be7539bb"
@@ -234,21 +238,17 @@ Content-Type: multipart/alternative; boundary="0000000000001d296c05be7539bb"
 Content-Type: text/plain; charset="UTF-8"
 
 This is a test file.
-
 --0000000000001d296c05be7539bb
 Content-Type: text/html; charset="UTF-8"
 
 <div dir="ltr">This is a test file.</div>
-
 --0000000000001d296c05be7539bb--
-
 --0000000000001d296f05be7539bd
 Content-Type: text/plain; charset="US-ASCII"; name="test.txt"
 Content-Disposition: attachment; filename="test.txt"
 Content-Transfer-Encoding: base64
 
 VGhpcyBpcyBhIHRlc3QgZmlsZS4K
-
 --0000000000001d296f05be7539bd--`,
 			),
 		},
@@ -286,43 +286,32 @@ VGhpcyBpcyBhIHRlc3QgZmlsZS4K
 				),
 				Content: strings.NewReader("This is a test message."),
 			},
-			want: []byte(`Content-Type: text/plain
+			want: crlf(`Content-Type: text/plain
 
 This is a test message.`,
 			),
 		},
 		{
-			name: "invalid content",
+			name: "bare line breaks in content",
 			fields: fields{
 				Header: gowl.NewHeader(
 					[]*gowl.Field{
 						gowl.NewField("Content-Type", []string{"text/plain"}),
 					},
 				),
-				Content: errReader{},
+				Content: strings.NewReader("Line one.\nLine two.\rLine three.\r\nLine four.\n"),
 			},
-			wantErr: true,
+			want: []byte("Content-Type: text/plain\r\n\r\nLine one.\r\nLine two.\r\nLine three.\r\nLine four.\r\n"),
 		},
 		{
-			name: "missing boundary in header",
+			name: "invalid content",
 			fields: fields{
 				Header: gowl.NewHeader(
 					[]*gowl.Field{
-						gowl.NewField("Content-Type", []string{"multipart/alternative"}),
+						gowl.NewField("Content-Type", []string{"text/plain"}),
 					},
 				),
-				Parts: []*gowl.Part{
-					gowl.NewPart(
-						gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain", "charset=\"UTF-8\""})}),
-						strings.NewReader("This is a test message."),
-						nil,
-					),
-					gowl.NewPart(
-						gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/html", "charset=\"UTF-8\""})}),
-						strings.NewReader("<div dir=\"ltr\">This is a test message.</div>"),
-						nil,
-					),
-				},
+				Content: errReader{},
 			},
 			wantErr: true,
 		},
@@ -366,3 +355,89 @@ This is a test message.`,
 		})
 	}
 }
+
+var ErrInvalidWriter = errors.New("invalid io.Writer")
+
+// limitWriter fails after n bytes are written.
+type limitWriter struct {
+	n int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+
+		return n, ErrInvalidWriter
+	}
+
+	w.n -= len(p)
+
+	return len(p), nil
+}
+
+func TestPart_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	newPart := func() *gowl.Part {
+		return gowl.NewPart(
+			gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"multipart/mixed", `boundary="part_12345"`})}),
+			nil,
+			[]*gowl.Part{
+				gowl.NewPart(
+					gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}),
+					strings.NewReader("This is a test message."),
+					nil,
+				),
+				gowl.NewPart(
+					gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"application/octet-stream"})}),
+					io.LimitReader(zeroReader{}, 1<<20),
+					nil,
+				),
+			},
+		)
+	}
+
+	want, err := newPart().Render()
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	n, err := newPart().WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(want)), n)
+	require.Equal(t, want, buf.Bytes())
+
+	for _, limit := range []int{0, 10, 60, 100, len(want) - 1} {
+		n, err = newPart().WriteTo(&limitWriter{n: limit})
+		require.ErrorIs(t, err, ErrInvalidWriter)
+		require.Equal(t, int64(limit), n)
+	}
+}
+
+func TestPart_WriteToRewind(t *testing.T) {
+	t.Parallel()
+
+	p := gowl.NewPart(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Content-Type", []string{"text/plain"})}),
+		strings.NewReader("This is a test message."),
+		nil,
+	)
+
+	first, err := p.Render()
+	require.NoError(t, err)
+
+	second, err := p.Render()
+	require.NoError(t, err)
+	require.Equal(t, string(first), string(second))
+}
+
+// zeroReader reads an infinite stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,274 @@
+package gowl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// PartReader walks the parts of a raw RFC 5322 message one by one without
+// materializing the whole Part tree. Only the part which is being read is held
+// by the PartReader, the content of the previous part is discarded when the
+// next part is requested.
+type PartReader struct {
+	r          *bufio.Reader
+	boundaries [][]byte
+	body       *partBody
+	started    bool
+	depth      int
+	err        error
+
+	// delim is the index of the boundary of the last read delimiter line,
+	// closing reports whether it was the closing delimiter.
+	delim   int
+	closing bool
+	eof     bool
+}
+
+// NewPartReader is a constructor of the PartReader.
+func NewPartReader(r io.Reader) *PartReader {
+	return &PartReader{
+		r:     bufio.NewReader(r),
+		delim: -1,
+	}
+}
+
+// Depth returns the nesting level of the Part returned by the last call of
+// NextPart. The root part of the message is at level 0, the parts of
+// a multipart Part are one level deeper than the multipart Part.
+func (pr *PartReader) Depth() int {
+	return pr.depth
+}
+
+// NextPart returns the next Part of the message in depth-first order, the parts
+// of a multipart Part directly follow it. The first Part is the root of the
+// message and its header holds all fields of the message header.
+//
+// The returned Part has no sub-parts. The content of a multipart Part is nil,
+// the content of other parts reads the body of the part decoded according to its
+// Content-Transfer-Encoding and it is valid only until the next call of NextPart.
+// It returns io.EOF when there are no more parts.
+func (pr *PartReader) NextPart() (*Part, error) {
+	if pr.err != nil {
+		return nil, pr.err
+	}
+
+	p, err := pr.nextPart()
+	if err != nil {
+		pr.err = err
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (pr *PartReader) nextPart() (*Part, error) {
+	if pr.body != nil {
+		if _, err := io.Copy(io.Discard, pr.body); err != nil {
+			return nil, fmt.Errorf("failed to skip part content: %w", err)
+		}
+
+		pr.body = nil
+	}
+
+	if !pr.started {
+		pr.started = true
+
+		return pr.readPart()
+	}
+
+	for !pr.eof {
+		i := pr.delim
+		if !pr.closing {
+			pr.boundaries = pr.boundaries[:i+1]
+
+			return pr.readPart()
+		}
+
+		// The epilogue is skipped up to a delimiter of an enclosing multipart part.
+		pr.boundaries = pr.boundaries[:i]
+		if err := pr.skip(); err != nil {
+			return nil, fmt.Errorf("failed to skip multipart epilogue: %w", err)
+		}
+	}
+
+	return nil, io.EOF
+}
+
+// readPart reads the header of the next part and opens its body.
+func (pr *PartReader) readPart() (*Part, error) {
+	fields, err := pr.readHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse part header: %w", err)
+	}
+
+	header := NewHeader(fields)
+	pr.depth = len(pr.boundaries)
+
+	if isMultipart(header) {
+		boundary, err := header.Boundary()
+		if err != nil {
+			return nil, err
+		}
+
+		pr.boundaries = append(pr.boundaries, boundary)
+
+		// The preamble is skipped up to the first delimiter.
+		if err := pr.skip(); err != nil {
+			return nil, fmt.Errorf("failed to skip multipart preamble: %w", err)
+		}
+
+		return NewPart(header, nil, nil), nil
+	}
+
+	pr.body = &partBody{pr: pr, lineStart: true}
+
+	content, err := decoder(pr.body, header.transferEncoding())
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPart(header, content, nil), nil
+}
+
+// readHeader reads the header lines up to the empty line and parses them.
+func (pr *PartReader) readHeader() ([]*Field, error) {
+	var data []byte
+
+	for {
+		line, err := pr.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		data = append(data, line...)
+
+		if err == io.EOF {
+			pr.eof = true
+		}
+
+		if err == io.EOF || len(bytes.TrimRight(line, "\r\n")) == 0 {
+			fields, _, err := parseHeader(data)
+
+			return fields, err
+		}
+	}
+}
+
+// skip discards the lines up to the next delimiter line.
+func (pr *PartReader) skip() error {
+	_, err := io.Copy(io.Discard, &partBody{pr: pr, lineStart: true})
+
+	return err
+}
+
+// delimiter returns the index of the boundary of the delimiter line and whether
+// it is the closing delimiter. The index is -1 if the line is not a delimiter.
+func (pr *PartReader) delimiter(line []byte) (int, bool) {
+	line = bytes.TrimRight(line, "\r\n")
+	if !bytes.HasPrefix(line, []byte("--")) {
+		return -1, false
+	}
+
+	for i := len(pr.boundaries) - 1; i >= 0; i-- {
+		if !bytes.HasPrefix(line[2:], pr.boundaries[i]) {
+			continue
+		}
+
+		switch rest := bytes.TrimRight(line[2+len(pr.boundaries[i]):], " \t"); string(rest) {
+		case "":
+			return i, false
+		case "--":
+			return i, true
+		}
+	}
+
+	return -1, false
+}
+
+// partBody reads the body of a part up to the next delimiter line. The line
+// break before the delimiter line belongs to the delimiter, so the line break
+// of every line is held back until the following line is read.
+type partBody struct {
+	pr        *PartReader
+	buf       []byte
+	hold      []byte
+	lineStart bool
+	err       error
+}
+
+func (b *partBody) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 && b.err == nil {
+		b.fill()
+	}
+
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+
+	if n > 0 {
+		return n, nil
+	}
+
+	return 0, b.err
+}
+
+// fill reads the next line or a fragment of a long line into the buffer.
+func (b *partBody) fill() {
+	pr := b.pr
+	if pr.eof {
+		b.err = io.EOF
+
+		return
+	}
+
+	line, err := pr.r.ReadSlice('\n')
+
+	switch err {
+	case nil, bufio.ErrBufferFull:
+	case io.EOF:
+		pr.eof = true
+	default:
+		b.err = err
+
+		return
+	}
+
+	if b.lineStart && len(pr.boundaries) > 0 {
+		if i, closing := pr.delimiter(line); i >= 0 {
+			pr.delim, pr.closing = i, closing
+			b.err = io.EOF
+
+			// The rest of a delimiter line which did not fit in the buffer is discarded.
+			for err == bufio.ErrBufferFull {
+				_, err = pr.r.ReadSlice('\n')
+			}
+
+			if err == io.EOF {
+				pr.eof = true
+			}
+
+			return
+		}
+	}
+
+	var brk []byte
+
+	if b.lineStart = err == nil; b.lineStart {
+		brk = []byte{'\n'}
+		if bytes.HasSuffix(line, crlf) {
+			brk = crlf
+		}
+
+		line = line[:len(line)-len(brk)]
+	}
+
+	b.buf = append(append(b.buf[:0], b.hold...), line...)
+	b.hold = brk
+
+	if pr.eof {
+		// The line break held back before the end of the message belongs to the content.
+		b.err = io.EOF
+	}
+}
@@ -0,0 +1,175 @@
+package gowl_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartReader_NextPart(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a", 10000)
+	raw := crlf(`From: Zoe <zoe@example.com>
+Subject: Report
+Content-Type: multipart/mixed; boundary="outer"
+
+This is a multi-part message in MIME format.
+--outer
+Content-Type: multipart/alternative; boundary=inner
+
+--inner
+Content-Type: text/plain; charset="UTF-8"
+Content-Transfer-Encoding: quoted-printable
+
+Gr=C3=BC=C3=9Fe
+
+--inner
+Content-Type: text/html; charset="UTF-8"
+
+<p>` + long + `</p>
+--inner--
+Inner epilogue.
+--outer
+Content-Type: text/plain; name="report.txt"
+Content-Transfer-Encoding: base64
+
+VGhpcyBpcyBhIHRlc3QgZmlsZS4K
+--outer--
+Epilogue.
+`)
+
+	type part struct {
+		depth       int
+		contentType string
+		content     string
+	}
+
+	want := []part{
+		{depth: 0, contentType: "multipart/mixed"},
+		{depth: 1, contentType: "multipart/alternative"},
+		{depth: 2, contentType: "text/plain", content: "Grüße\r\n"},
+		{depth: 2, contentType: "text/html", content: "<p>" + long + "</p>"},
+		{depth: 1, contentType: "text/plain", content: "This is a test file.\n"},
+	}
+
+	pr := gowl.NewPartReader(bytes.NewReader(raw))
+
+	var got []part
+
+	for {
+		p, err := pr.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+		require.Nil(t, p.Parts())
+
+		var content []byte
+		if p.Content() != nil {
+			content, err = io.ReadAll(p.Content())
+			require.NoError(t, err)
+		}
+
+		var contentType string
+
+		for _, f := range p.Header().Fields() {
+			if f.Name() == "Content-Type" {
+				contentType = f.Values()[0]
+			}
+		}
+
+		got = append(got, part{depth: pr.Depth(), contentType: contentType, content: string(content)})
+	}
+
+	require.Equal(t, want, got)
+
+	_, err := pr.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestPartReader_SkipContent(t *testing.T) {
+	t.Parallel()
+
+	msg := gowl.NewMessage(
+		gowl.NewHeader([]*gowl.Field{gowl.NewField("Subject", []string{"Test"})}),
+		gowl.NewPart(gowl.NewHeader(nil), nil, textParts()),
+	)
+
+	raw, err := msg.Render()
+	require.NoError(t, err)
+
+	pr := gowl.NewPartReader(bytes.NewReader(raw))
+
+	root, err := pr.NextPart()
+	require.NoError(t, err)
+	require.Len(t, root.Header().Fields(), 2)
+
+	// The content of the first part is never read.
+	_, err = pr.NextPart()
+	require.NoError(t, err)
+
+	html, err := pr.NextPart()
+	require.NoError(t, err)
+
+	content, err := io.ReadAll(html.Content())
+	require.NoError(t, err)
+	require.Equal(t, `<div dir="ltr">This is a test message.</div>`, string(content))
+
+	_, err = pr.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestPartReader_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		r       io.Reader
+		wantErr error
+	}{
+		{
+			name:    "invalid reader",
+			r:       errReader{},
+			wantErr: ErrInvalidReader,
+		},
+		{
+			name:    "invalid header",
+			r:       strings.NewReader("Subject Hello\r\n\r\nHello"),
+			wantErr: gowl.ErrInvalidHeader,
+		},
+		{
+			name:    "missing boundary",
+			r:       strings.NewReader("Content-Type: multipart/mixed\r\n\r\nHello"),
+			wantErr: gowl.ErrNoBoundary,
+		},
+		{
+			name:    "unknown encoding",
+			r:       strings.NewReader("Content-Transfer-Encoding: x-uuencode\r\n\r\nHello"),
+			wantErr: gowl.ErrUnknownEncoding,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pr := gowl.NewPartReader(tt.r)
+
+			p, err := pr.NextPart()
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Nil(t, p)
+
+			// The error is sticky.
+			_, err = pr.NextPart()
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
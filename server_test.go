@@ -0,0 +1,491 @@
+package gowl_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMail is a mail transaction received by the fakeServer.
+type fakeMail struct {
+	From   string
+	To     []string
+	Data   string
+	Secure bool
+	User   string
+	UTF8   bool
+}
+
+// fakeServer is an in-process SMTP server used to test the Client.
+type fakeServer struct {
+	ln         net.Listener
+	extensions []string
+	noEHLO     bool
+	tlsConfig  *tls.Config
+
+	mu       sync.Mutex
+	users    map[string]string
+	commands []string
+	mails    []fakeMail
+	wg       sync.WaitGroup
+}
+
+// newFakeServer starts a fakeServer advertising the given extensions.
+func newFakeServer(t *testing.T, extensions ...string) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return startFakeServer(t, ln, nil, extensions)
+}
+
+// newFakeTLSServer starts a fakeServer with a self-signed certificate
+// advertising the given extensions. If implicit is true, the fakeServer
+// accepts TLS connections only (SMTPS), otherwise it supports STARTTLS.
+// It returns the fakeServer and a client TLS configuration trusting it.
+func newFakeTLSServer(t *testing.T, implicit bool, extensions ...string) (*fakeServer, *tls.Config) {
+	t.Helper()
+
+	cert, pool := testCertificate(t)
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	if implicit {
+		ln = tls.NewListener(ln, config)
+	} else {
+		extensions = append(extensions, "STARTTLS")
+	}
+
+	s := startFakeServer(t, ln, config, extensions)
+
+	return s, &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+}
+
+func startFakeServer(t *testing.T, ln net.Listener, config *tls.Config, extensions []string) *fakeServer {
+	t.Helper()
+
+	s := &fakeServer{
+		ln:         ln,
+		extensions: extensions,
+		tlsConfig:  config,
+	}
+
+	s.wg.Add(1)
+
+	go s.serve()
+
+	t.Cleanup(func() {
+		_ = ln.Close()
+		s.wg.Wait()
+	})
+
+	return s
+}
+
+// Addr returns the address the fakeServer listens on.
+func (s *fakeServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// HostPort returns the host and the port the fakeServer listens on.
+func (s *fakeServer) HostPort() (string, int) {
+	addr := s.ln.Addr().(*net.TCPAddr)
+
+	return addr.IP.String(), addr.Port
+}
+
+// AddUser registers a user with the given secret. The secret is
+// the password or the bearer token of the user.
+func (s *fakeServer) AddUser(user, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.users == nil {
+		s.users = make(map[string]string)
+	}
+
+	s.users[user] = secret
+}
+
+func (s *fakeServer) checkUser(user, secret string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	want, ok := s.users[user]
+
+	return ok && want == secret
+}
+
+func (s *fakeServer) secret(user string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.users[user]
+}
+
+// Commands returns all commands received by the fakeServer.
+func (s *fakeServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.commands...)
+}
+
+// Mails returns all mail transactions received by the fakeServer.
+func (s *fakeServer) Mails() []fakeMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]fakeMail(nil), s.mails...)
+}
+
+func (s *fakeServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	defer func() { _ = text.Close() }()
+
+	_, secure := conn.(*tls.Conn)
+
+	_ = text.PrintfLine("220 fake.example.com ESMTP ready")
+
+	var (
+		mail *fakeMail
+		user string
+	)
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if s.noEHLO {
+				_ = text.PrintfLine("502 command not implemented")
+
+				continue
+			}
+
+			lines := []string{"fake.example.com greets " + arg}
+			for _, ext := range s.extensions {
+				if !secure || ext != "STARTTLS" {
+					lines = append(lines, ext)
+				}
+			}
+
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+
+				_ = text.PrintfLine("250%s%s", sep, l)
+			}
+		case "HELO":
+			_ = text.PrintfLine("250 fake.example.com greets %s", arg)
+		case "MAIL":
+			if mail != nil {
+				_ = text.PrintfLine("503 nested MAIL command")
+
+				continue
+			}
+
+			mail = &fakeMail{
+				From:   envelopeAddress(arg),
+				Secure: secure,
+				User:   user,
+				UTF8:   strings.HasSuffix(strings.ToUpper(arg), " SMTPUTF8"),
+			}
+			_ = text.PrintfLine("250 OK")
+		case "RCPT":
+			if mail == nil {
+				_ = text.PrintfLine("503 bad sequence of commands")
+
+				continue
+			}
+
+			addr := envelopeAddress(arg)
+			if strings.HasPrefix(addr, "reject") {
+				_ = text.PrintfLine("550 mailbox unavailable")
+
+				continue
+			}
+
+			mail.To = append(mail.To, addr)
+			_ = text.PrintfLine("250 OK")
+		case "DATA":
+			if mail == nil || len(mail.To) == 0 {
+				_ = text.PrintfLine("503 bad sequence of commands")
+
+				continue
+			}
+
+			_ = text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			mail.Data = string(data)
+
+			s.mu.Lock()
+			s.mails = append(s.mails, *mail)
+			s.mu.Unlock()
+
+			mail = nil
+			_ = text.PrintfLine("250 OK queued")
+		case "STARTTLS":
+			if s.tlsConfig == nil || secure {
+				_ = text.PrintfLine("502 command not implemented")
+
+				continue
+			}
+
+			_ = text.PrintfLine("220 ready to start TLS")
+
+			conn = tls.Server(conn, s.tlsConfig)
+			text = textproto.NewConn(conn)
+			secure = true
+			mail = nil
+		case "AUTH":
+			if user != "" {
+				_ = text.PrintfLine("503 already authenticated")
+
+				continue
+			}
+
+			var ok, malformed bool
+
+			user, ok = s.authenticate(text, arg, &malformed)
+
+			switch {
+			case ok:
+				_ = text.PrintfLine("235 authentication successful")
+			case malformed:
+				user = ""
+				_ = text.PrintfLine("501 malformed authentication response")
+			default:
+				user = ""
+				_ = text.PrintfLine("535 authentication credentials invalid")
+			}
+		case "RSET":
+			mail = nil
+			_ = text.PrintfLine("250 OK")
+		case "NOOP":
+			_ = text.PrintfLine("250 OK")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+
+			return
+		default:
+			_ = text.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// authenticate performs the authentication exchange of the given mechanism
+// and returns the authenticated user. It sets malformed if a response to
+// a challenge is not valid base64, e.g. the "=" allowed only for an empty
+// initial response.
+func (s *fakeServer) authenticate(text *textproto.Conn, arg string, malformed *bool) (string, bool) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	challenge := func(c string) (string, bool) {
+		_ = text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(c)))
+
+		line, err := text.ReadLine()
+		if err != nil || line == "*" {
+			return "", false
+		}
+
+		b, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			*malformed = true
+
+			return "", false
+		}
+
+		return string(b), true
+	}
+
+	ir, ok := "", true
+	if len(fields) > 1 {
+		if ir, ok = decodeAuth(fields[1]); !ok {
+			return "", false
+		}
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "PLAIN":
+		if len(fields) == 1 {
+			if ir, ok = challenge(""); !ok {
+				return "", false
+			}
+		}
+
+		parts := strings.Split(ir, "\x00")
+		if len(parts) != 3 {
+			return "", false
+		}
+
+		return parts[1], s.checkUser(parts[1], parts[2])
+	case "LOGIN":
+		u, ok := challenge("Username:")
+		if !ok {
+			return "", false
+		}
+
+		p, ok := challenge("Password:")
+
+		return u, ok && s.checkUser(u, p)
+	case "CRAM-MD5":
+		nonce := "<1896.697170952@fake.example.com>"
+
+		resp, ok := challenge(nonce)
+		if !ok {
+			return "", false
+		}
+
+		parts := strings.SplitN(resp, " ", 2)
+		if len(parts) != 2 {
+			return "", false
+		}
+
+		h := hmac.New(md5.New, []byte(s.secret(parts[0])))
+		h.Write([]byte(nonce))
+
+		return parts[0], s.secret(parts[0]) != "" && hex.EncodeToString(h.Sum(nil)) == parts[1]
+	case "XOAUTH2", "OAUTHBEARER":
+		var u, token string
+
+		for _, kv := range strings.Split(ir, "\x01") {
+			switch {
+			case strings.HasPrefix(kv, "user="):
+				u = strings.TrimPrefix(kv, "user=")
+			case strings.HasPrefix(kv, "n,a="):
+				u = strings.TrimSuffix(strings.TrimPrefix(kv, "n,a="), ",")
+			case strings.HasPrefix(kv, "auth=Bearer "):
+				token = strings.TrimPrefix(kv, "auth=Bearer ")
+			}
+		}
+
+		if s.checkUser(u, token) {
+			return u, true
+		}
+
+		// Send the error challenge the client has to acknowledge with an empty response.
+		if ack, ok := challenge(`{"status":"401","schemes":"bearer"}`); ok && ack != "" {
+			*malformed = true
+		}
+
+		return "", false
+	default:
+		return "", false
+	}
+}
+
+// decodeAuth decodes the initial response of the AUTH command.
+func decodeAuth(s string) (string, bool) {
+	if s == "=" {
+		return "", true
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+
+	return string(b), err == nil
+}
+
+// envelopeAddress extracts the address from the MAIL and RCPT command argument.
+func envelopeAddress(arg string) string {
+	start := strings.IndexByte(arg, '<')
+	end := strings.IndexByte(arg, '>')
+
+	if start < 0 || end < start {
+		return ""
+	}
+
+	return arg[start+1 : end]
+}
+
+// testCertificate generates a self-signed certificate for the loopback
+// address and returns it with a pool trusting it.
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"fake.example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
@@ -0,0 +1,202 @@
+package gowl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htemplate "html/template"
+	"io/fs"
+	"strings"
+	"sync"
+	ttemplate "text/template"
+)
+
+// ErrNoTemplateBody is returned when a Template has neither a text nor an HTML body.
+var ErrNoTemplateBody = errors.New("the template has neither a text nor an HTML body")
+
+// Suffixes of the files of a template loaded by the TemplateStore.
+const (
+	subjectTemplateSuffix = ".subject.tmpl"
+	textTemplateSuffix    = ".txt.tmpl"
+	htmlTemplateSuffix    = ".html.tmpl"
+)
+
+// Template is a paired text/template and html/template of the message bodies
+// with a text/template of the subject. It is safe for concurrent use.
+type Template struct {
+	subject *ttemplate.Template
+	text    *ttemplate.Template
+	html    *htemplate.Template
+}
+
+// NewTemplate parses the templates of the subject, the text/plain and the text/html
+// body with the functions. Empty templates are omitted, but at least one of the
+// bodies is required.
+func NewTemplate(subject, text, html string, funcs map[string]interface{}) (*Template, error) {
+	if text == "" && html == "" {
+		return nil, ErrNoTemplateBody
+	}
+
+	t := &Template{}
+
+	var err error
+
+	if subject != "" {
+		if t.subject, err = ttemplate.New("subject").Funcs(funcs).Parse(subject); err != nil {
+			return nil, fmt.Errorf("failed to parse subject template: %w", err)
+		}
+	}
+
+	if text != "" {
+		if t.text, err = ttemplate.New("text").Funcs(funcs).Parse(text); err != nil {
+			return nil, fmt.Errorf("failed to parse text template: %w", err)
+		}
+	}
+
+	if html != "" {
+		if t.html, err = htemplate.New("html").Funcs(funcs).Parse(html); err != nil {
+			return nil, fmt.Errorf("failed to parse HTML template: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+// Execute executes the templates with the data, e.g. of a single recipient. It returns
+// the subject and the body Part, which is a multipart/alternative Part if the Template
+// has both bodies. The subject is collapsed to a single line.
+func (t *Template) Execute(data interface{}) (string, *Part, error) {
+	subject, text, html, err := t.execute(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch {
+	case text != nil && html != nil:
+		return subject, NewAlternative(text, html), nil
+	case text != nil:
+		return subject, text, nil
+	default:
+		return subject, html, nil
+	}
+}
+
+// execute executes the templates and returns the subject and the parts of the bodies.
+func (t *Template) execute(data interface{}) (string, *Part, *Part, error) {
+	var (
+		subject    string
+		text, html *Part
+		buf        bytes.Buffer
+	)
+
+	if t.subject != nil {
+		if err := t.subject.Execute(&buf, data); err != nil {
+			return "", nil, nil, fmt.Errorf("failed to execute subject template: %w", err)
+		}
+
+		subject = strings.Join(strings.Fields(buf.String()), " ")
+	}
+
+	if t.text != nil {
+		buf = bytes.Buffer{}
+		if err := t.text.Execute(&buf, data); err != nil {
+			return "", nil, nil, fmt.Errorf("failed to execute text template: %w", err)
+		}
+
+		text = newTextPart(mediaTypeText, bytes.NewReader(buf.Bytes()))
+	}
+
+	if t.html != nil {
+		buf = bytes.Buffer{}
+		if err := t.html.Execute(&buf, data); err != nil {
+			return "", nil, nil, fmt.Errorf("failed to execute HTML template: %w", err)
+		}
+
+		html = newTextPart(mediaTypeHTML, bytes.NewReader(buf.Bytes()))
+	}
+
+	return subject, text, html, nil
+}
+
+// TemplateStore loads the templates from a file system and caches them after parsing.
+// A template named "welcome" consists of the files "welcome.subject.tmpl",
+// "welcome.txt.tmpl" and "welcome.html.tmpl", any of which may be missing as
+// described in NewTemplate. It is safe for concurrent use.
+type TemplateStore struct {
+	fsys  fs.FS
+	funcs map[string]interface{}
+
+	mu    sync.Mutex
+	cache map[string]*Template
+}
+
+// NewTemplateStore is a constructor of the TemplateStore. The templates are parsed
+// with the functions.
+func NewTemplateStore(fsys fs.FS, funcs map[string]interface{}) *TemplateStore {
+	return &TemplateStore{
+		fsys:  fsys,
+		funcs: funcs,
+		cache: make(map[string]*Template),
+	}
+}
+
+// Template returns the named template, it is loaded and parsed on the first use.
+func (s *TemplateStore) Template(name string) (*Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.cache[name]; ok {
+		return t, nil
+	}
+
+	sources := make([]string, 0, 3)
+
+	for _, suffix := range []string{subjectTemplateSuffix, textTemplateSuffix, htmlTemplateSuffix} {
+		data, err := fs.ReadFile(s.fsys, name+suffix)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+
+		sources = append(sources, string(data))
+	}
+
+	t, err := NewTemplate(sources[0], sources[1], sources[2], s.funcs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template %s: %w", name, err)
+	}
+
+	s.cache[name] = t
+
+	return t, nil
+}
+
+// Execute executes the named template with the data, see Template.Execute.
+func (s *TemplateStore) Execute(name string, data interface{}) (string, *Part, error) {
+	t, err := s.Template(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return t.Execute(data)
+}
+
+// Template executes the template with the data and sets the subject and the bodies
+// of the Message. The subject is kept if the template has none.
+func (b *MessageBuilder) Template(t *Template, data interface{}) *MessageBuilder {
+	subject, text, html, err := t.execute(data)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+
+		return b
+	}
+
+	if subject != "" {
+		b.subject = subject
+	}
+
+	b.text, b.html = text, html
+
+	return b
+}
@@ -0,0 +1,154 @@
+package gowl_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/chutommy/gowl"
+	"github.com/stretchr/testify/require"
+)
+
+// recipient is the data of the executed templates.
+type recipient struct {
+	Name  string
+	Items []string
+}
+
+// partContent returns the content of the Part.
+func partContent(t *testing.T, p *gowl.Part) string {
+	t.Helper()
+
+	data, err := io.ReadAll(p.Content())
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestTemplate_Execute(t *testing.T) {
+	t.Parallel()
+
+	funcs := map[string]interface{}{"upper": strings.ToUpper}
+
+	tmpl, err := gowl.NewTemplate(
+		"Order for\n{{ upper .Name }}",
+		"Hello {{ .Name }}, you ordered {{ len .Items }} items.",
+		"<p>Hello {{ .Name }}</p><ul>{{ range .Items }}<li>{{ . }}</li>{{ end }}</ul>",
+		funcs,
+	)
+	require.NoError(t, err)
+
+	subject, p, err := tmpl.Execute(recipient{Name: "Tom & Jerry", Items: []string{"<cheese>", "milk"}})
+	require.NoError(t, err)
+	require.Equal(t, "Order for TOM & JERRY", subject)
+
+	mediaType, _, err := p.Header().ContentType()
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+	require.Len(t, p.Parts(), 2)
+	require.Equal(t, "Hello Tom & Jerry, you ordered 2 items.", partContent(t, p.Parts()[0]))
+	require.Equal(t, "<p>Hello Tom &amp; Jerry</p><ul><li>&lt;cheese&gt;</li><li>milk</li></ul>",
+		partContent(t, p.Parts()[1]))
+
+	// Every execution produces new parts.
+	_, other, err := tmpl.Execute(recipient{Name: "Jane"})
+	require.NoError(t, err)
+	require.Equal(t, "Hello Jane, you ordered 0 items.", partContent(t, other.Parts()[0]))
+
+	_, _, err = tmpl.Execute(nil)
+	require.Error(t, err)
+
+	tmpl, err = gowl.NewTemplate("", "Hello {{ . }}", "", nil)
+	require.NoError(t, err)
+
+	subject, p, err = tmpl.Execute("Jane")
+	require.NoError(t, err)
+	require.Empty(t, subject)
+
+	mediaType, _, err = p.Header().ContentType()
+	require.NoError(t, err)
+	require.Equal(t, "text/plain", mediaType)
+
+	_, err = gowl.NewTemplate("Subject", "", "", nil)
+	require.ErrorIs(t, err, gowl.ErrNoTemplateBody)
+
+	_, err = gowl.NewTemplate("", "{{ .Name", "", nil)
+	require.Error(t, err)
+
+	_, err = gowl.NewTemplate("", "", "{{ unknown }}", nil)
+	require.Error(t, err)
+}
+
+func TestTemplateStore(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"welcome.subject.tmpl":     {Data: []byte("Welcome, {{ .Name }}!")},
+		"welcome.txt.tmpl":         {Data: []byte("Hi {{ .Name }}")},
+		"welcome.html.tmpl":        {Data: []byte("<b>Hi {{ .Name }}</b>")},
+		"reset.html.tmpl":          {Data: []byte("<a href=\"{{ . }}\">Reset</a>")},
+		"broken.txt.tmpl":          {Data: []byte("{{ if }}")},
+		"subjectonly.subject.tmpl": {Data: []byte("Subject")},
+	}
+
+	s := gowl.NewTemplateStore(fsys, nil)
+
+	tmpl, err := s.Template("welcome")
+	require.NoError(t, err)
+
+	cached, err := s.Template("welcome")
+	require.NoError(t, err)
+	require.Same(t, tmpl, cached)
+
+	subject, p, err := s.Execute("welcome", recipient{Name: "Jane"})
+	require.NoError(t, err)
+	require.Equal(t, "Welcome, Jane!", subject)
+	require.Len(t, p.Parts(), 2)
+
+	subject, p, err = s.Execute("reset", "https://example.com/reset?token=a b")
+	require.NoError(t, err)
+	require.Empty(t, subject)
+	require.Equal(t, `<a href="https://example.com/reset?token=a%20b">Reset</a>`, partContent(t, p))
+
+	_, err = s.Template("broken")
+	require.Error(t, err)
+
+	_, err = s.Template("subjectonly")
+	require.ErrorIs(t, err, gowl.ErrNoTemplateBody)
+
+	_, err = s.Template("missing")
+	require.ErrorIs(t, err, gowl.ErrNoTemplateBody)
+}
+
+func TestMessageBuilder_Template(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := gowl.NewTemplate("Hello {{ . }}", "Hello {{ . }}", "<p>Hello {{ . }}</p>", nil)
+	require.NoError(t, err)
+
+	for _, name := range []string{"David", "Thomas"} {
+		msg, err := gowl.NewMessageBuilder().
+			From("john.doe@example.com").
+			To(strings.ToLower(name)+"@example.com").
+			Template(tmpl, name).
+			Build()
+		require.NoError(t, err)
+
+		data, err := msg.Render()
+		require.NoError(t, err)
+
+		parsed, err := gowl.ParseMessage(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, []string{"Hello " + name}, parsed.Header().Get("Subject").Values())
+		require.Equal(t, "multipart/alternative", partTree(t, parsed.RootPart())[:21])
+		require.Equal(t, "<p>Hello "+name+"</p>", partContent(t, parsed.RootPart().Parts()[1]))
+	}
+
+	tmpl, err = gowl.NewTemplate("", "{{ .Missing.Field }}", "", nil)
+	require.NoError(t, err)
+
+	_, err = gowl.NewMessageBuilder().From("john.doe@example.com").Template(tmpl, struct{}{}).Build()
+	require.Error(t, err)
+}